@@ -1,35 +1,153 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 )
 
 // ExportData структура для всего экспортируемого проекта
 type ExportData struct {
-	Metadata ProjectMetadata `json:"metadata"`
-	Sheets   []SheetData     `json:"sheets"`
+	Metadata     ProjectMetadata `json:"metadata"`
+	Sheets       []SheetData     `json:"sheets"`
+	DefinedNames []DefinedName   `json:"defined_names,omitempty"`
+}
+
+// DefinedName описывает именованный диапазон. Если Sheet не задан, имя
+// регистрируется на уровне книги, иначе — только в области видимости листа.
+type DefinedName struct {
+	Name     string `json:"name"`
+	RefersTo string `json:"refers_to"`
+	Sheet    string `json:"sheet,omitempty"`
 }
 
 // ProjectMetadata метаданные проекта
 type ProjectMetadata struct {
-	ProjectName string `json:"project_name"`
-	Author      string `json:"author"`
-	CreatedAt   string `json:"created_at"`
+	ProjectName string       `json:"project_name"`
+	Author      string       `json:"author"`
+	CreatedAt   string       `json:"created_at"`
+	Columns     []ColumnMeta `json:"columns,omitempty"`      // заполняется при импорте, если во входном файле есть мета-заголовок
+	ActiveSheet int          `json:"active_sheet,omitempty"` // индекс (в Sheets) листа, который должен быть активным при открытии
+}
+
+// ColumnMeta описывает одну колонку мета-заголовка: под каким ключом её
+// публиковать, как приводить тип значений и на каком месте она стоит.
+type ColumnMeta struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"` // int, float, bool, date, string, json
+	Index int    `json:"index"`
 }
 
 // SheetData структура для данных одного листа
 type SheetData struct {
-	Name    string       `json:"name"`
-	Data    [][]*string  `json:"data"` // nil для пустых ячеек
-	Formulas []Formula   `json:"formulas,omitempty"`
-	Styles   []Style     `json:"styles,omitempty"`
-	Charts   []Chart     `json:"charts,omitempty"`
+	Name               string                   `json:"name"`
+	Data               [][]*string              `json:"data"`                 // nil для пустых ячеек
+	TypedRows          []map[string]interface{} `json:"typed_rows,omitempty"` // строки после мета-заголовка, приведённые к типам из ProjectMetadata.Columns
+	Formulas           []Formula                `json:"formulas,omitempty"`
+	Styles             []Style                  `json:"styles,omitempty"`
+	Charts             []Chart                  `json:"charts,omitempty"`
+	ConditionalFormats []CondRule               `json:"conditional_formats,omitempty"`
+
+	FrozenPanes  *FrozenPanes       `json:"frozen_panes,omitempty"`
+	Hidden       bool               `json:"hidden,omitempty"`
+	VeryHidden   bool               `json:"very_hidden,omitempty"`
+	TabColor     string             `json:"tab_color,omitempty"` // RGB hex, e.g. "FF0000"
+	MergedCells  []string           `json:"merged_cells,omitempty"`
+	ColumnWidths map[string]float64 `json:"column_widths,omitempty"` // ключ — буква колонки, например "A"
+	RowHeights   map[string]float64 `json:"row_heights,omitempty"`   // ключ — номер строки в виде строки, например "1"
+	AutoFilter   string             `json:"auto_filter,omitempty"`   // диапазон, например "A1:D1"
+	Protection   *SheetProtection   `json:"protection,omitempty"`
+	PageSetup    *PageSetup         `json:"page_setup,omitempty"`
+
+	Validations []DataValidation `json:"validations,omitempty"`
+	Hyperlinks  []Hyperlink      `json:"hyperlinks,omitempty"`
+	Comments    []Comment        `json:"comments,omitempty"`
+	Images      []Image          `json:"images,omitempty"`
+}
+
+// DataValidation описывает правило проверки данных для диапазона ячеек.
+// Type: "list", "whole", "decimal", "date", "textLength", "custom".
+// Operator: "between", "notBetween", "equal", "notEqual", "greaterThan",
+// "greaterThanOrEqual", "lessThan", "lessThanOrEqual" (игнорируется для "list"/"custom").
+type DataValidation struct {
+	Range        string `json:"range"`
+	Type         string `json:"type"`
+	Operator     string `json:"operator,omitempty"`
+	Formula1     string `json:"formula1,omitempty"`
+	Formula2     string `json:"formula2,omitempty"`
+	AllowBlank   bool   `json:"allow_blank,omitempty"`
+	InputTitle   string `json:"input_title,omitempty"`
+	InputMessage string `json:"input_message,omitempty"`
+	ErrorStyle   string `json:"error_style,omitempty"` // "stop", "warning", "information"
+	ErrorTitle   string `json:"error_title,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// Hyperlink описывает гиперссылку на одной ячейке. Type — "External"
+// (обычный URL) или "Location" (ссылка на диапазон внутри книги).
+type Hyperlink struct {
+	Cell    string `json:"cell"`
+	Target  string `json:"target"`
+	Type    string `json:"type,omitempty"`
+	Tooltip string `json:"tooltip,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// Comment описывает заметку/комментарий к ячейке. Threaded переключает
+// между f.AddComment (классическая заметка) и f.AddThreadedComment
+// (современный тред-комментарий, привязанный к автору).
+type Comment struct {
+	Cell     string `json:"cell"`
+	Author   string `json:"author,omitempty"`
+	Text     string `json:"text"`
+	Threaded bool   `json:"threaded,omitempty"`
+}
+
+// Image описывает изображение, встраиваемое в ячейку из base64-пейлоада.
+type Image struct {
+	Cell      string  `json:"cell"`
+	Data      string  `json:"data"`      // base64-encoded содержимое файла
+	Extension string  `json:"extension"` // ".png", ".jpg", ".gif", ...
+	ScaleX    float64 `json:"scale_x,omitempty"`
+	ScaleY    float64 `json:"scale_y,omitempty"`
+	OffsetX   int     `json:"offset_x,omitempty"`
+	OffsetY   int     `json:"offset_y,omitempty"`
+}
+
+// FrozenPanes описывает количество замороженных первых строк/колонок листа.
+type FrozenPanes struct {
+	Rows int `json:"rows,omitempty"`
+	Cols int `json:"cols,omitempty"`
+}
+
+// SheetProtection описывает защиту листа паролем и разрешённые операции.
+type SheetProtection struct {
+	Password          string `json:"password,omitempty"`
+	AllowSelectLocked bool   `json:"allow_select_locked_cells,omitempty"`
+	AllowFormatCells  bool   `json:"allow_format_cells,omitempty"`
+	AllowSort         bool   `json:"allow_sort,omitempty"`
+	AllowAutoFilter   bool   `json:"allow_auto_filter,omitempty"`
+	AllowInsertRows   bool   `json:"allow_insert_rows,omitempty"`
+	AllowDeleteRows   bool   `json:"allow_delete_rows,omitempty"`
+}
+
+// PageSetup описывает параметры печати листа.
+type PageSetup struct {
+	Orientation string `json:"orientation,omitempty"` // "portrait" или "landscape"
+	PaperSize   int    `json:"paper_size,omitempty"`  // код бумаги Excelize, например 9 = A4
+	PrintArea   string `json:"print_area,omitempty"`
+	Header      string `json:"header,omitempty"`
+	Footer      string `json:"footer,omitempty"`
 }
 
 // Formula структура для формулы
@@ -38,81 +156,1253 @@ type Formula struct {
 	Formula string `json:"formula"`
 }
 
-// Style структура для стиля (упрощённая для примера)
-// В реальной реализации нужно будет отобразить все свойства стилей из Python
+// Style структура для стиля. Содержимое Style — это словарь атрибутов,
+// зеркалирующий то, что openpyxl/Python-сторона присылает для диапазона ячеек
+// (font, fill, border, number_format, alignment, protection).
 type Style struct {
 	Range string                 `json:"range"`
 	Style map[string]interface{} `json:"style"`
 }
 
+// CondRule описывает одно правило условного форматирования для диапазона.
+// Type соответствует типам правил Excelize: "cellIs", "colorScale", "dataBar",
+// "top10", "duplicateValues", "expression".
+type CondRule struct {
+	Range    string                 `json:"range"`
+	Type     string                 `json:"type"`
+	Criteria string                 `json:"criteria,omitempty"`
+	Value    string                 `json:"value,omitempty"`
+	MinValue string                 `json:"min_value,omitempty"`
+	MidValue string                 `json:"mid_value,omitempty"`
+	MaxValue string                 `json:"max_value,omitempty"`
+	MinColor string                 `json:"min_color,omitempty"`
+	MidColor string                 `json:"mid_color,omitempty"`
+	MaxColor string                 `json:"max_color,omitempty"`
+	BarColor string                 `json:"bar_color,omitempty"`
+	Rank     int                    `json:"rank,omitempty"`
+	Percent  bool                   `json:"percent,omitempty"`
+	Bottom   bool                   `json:"bottom,omitempty"`
+	Formula  string                 `json:"formula,omitempty"`
+	Style    map[string]interface{} `json:"style,omitempty"`
+}
+
 // Chart структура для диаграммы
 type Chart struct {
-	Type     string       `json:"type"`
-	Position string       `json:"position"`
-	Title    string       `json:"title,omitempty"`
-	Series   []ChartSeries `json:"series"`
+	Type          string           `json:"type"`
+	Position      string           `json:"position"`
+	Title         string           `json:"title,omitempty"`
+	Series        []ChartSeries    `json:"series"`
+	Grouping      string           `json:"grouping,omitempty"` // "clustered", "stacked" или "percentStacked"; по умолчанию берётся ChartSpec.DefaultGrouping
+	Overlap       *int             `json:"overlap,omitempty"`
+	GapWidth      *int             `json:"gap_width,omitempty"`
+	SecondaryAxis []bool           `json:"secondary_axis,omitempty"` // параллельно Series: true переносит эту серию в combo-диаграмму на вторичной оси
+	Legend        *ChartLegend     `json:"legend,omitempty"`
+	PlotArea      *ChartPlotArea   `json:"plot_area,omitempty"`
+	XAxis         *ChartAxis       `json:"x_axis,omitempty"`
+	YAxis         *ChartAxis       `json:"y_axis,omitempty"`
+	DataLabels    bool             `json:"data_labels,omitempty"`
+	TrendLine     []ChartTrendLine `json:"trend_line,omitempty"` // пока не применяется, см. buildChartConfig
 }
 
 // ChartSeries структура для серии диаграммы
 type ChartSeries struct {
-	Name       string `json:"name"`
-	Categories string `json:"categories"`
-	Values     string `json:"values"`
-}
-
-// convertChartType преобразует строку типа диаграммы из JSON в excelize.ChartType.
-// Внимание: Поддерживаются только базовые типы, доступные в Excelize v2.9.1.
-// Сложные типы (например, ColStacked, LinePercentStacked, Pie3D, DoughnutExploded)
-// могут требовать обновления Excelize или более сложной реализации.
-func convertChartType(chartTypeStr string) excelize.ChartType {
-	switch chartTypeStr {
-	case "col":
-		return excelize.Col
-	case "col3D": // Доступен в v2.9.1
-		return excelize.Col3D
-	case "line":
-		return excelize.Line
-	case "pie":
-		return excelize.Pie
-	case "pie3D": // Доступен в v2.9.1
-		return excelize.Pie3D
-	case "doughnut":
-		return excelize.Doughnut
-	case "bar": // Добавлен для полноты, если используется
-		return excelize.Bar
-	case "area": // Добавлен для полноты, если используется
-		return excelize.Area
-	case "radar": // Добавлен для полноты, если используется
-		return excelize.Radar
-	case "scatter": // Добавлен для полноты, если используется
-		return excelize.Scatter
-	case "stock": // Добавлен для полноты, если используется
-		return excelize.Stock
-	case "combo": // Добавлен для полноты, если используется
-		return excelize.Combo
-	// Следующие типы НЕ поддерживаются в Excelize v2.9.1 и возвращают базовый тип 'Col'
-	case "colStacked", "colPercentStacked", "col3DClustered", "col3DStacked", "col3DPercentStacked",
-		"lineStacked", "linePercentStacked", "line3D", "pieOfPie", "barOfPie", "doughnutExploded":
-		fmt.Printf("Warning: Chart type '%s' is not supported in Excelize v2.9.1, using 'col' as default.\n", chartTypeStr)
-		return excelize.Col
+	Name       string       `json:"name"`
+	Categories string       `json:"categories"`
+	Values     string       `json:"values"`
+	Fill       string       `json:"fill,omitempty"` // RGB hex, e.g. "FF0000"
+	Line       *ChartLine   `json:"line,omitempty"`
+	Marker     *ChartMarker `json:"marker,omitempty"`
+}
+
+// ChartLine описывает линию серии (для line/scatter/combo диаграмм).
+type ChartLine struct {
+	Smooth bool    `json:"smooth,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+}
+
+// ChartMarker описывает маркер точек серии.
+type ChartMarker struct {
+	Symbol string `json:"symbol,omitempty"`
+	Size   int    `json:"size,omitempty"`
+}
+
+// ChartLegend описывает легенду диаграммы.
+type ChartLegend struct {
+	Position      string `json:"position,omitempty"` // top, bottom, left, right, top_right
+	ShowLegendKey bool   `json:"show_legend_key,omitempty"`
+}
+
+// ChartPlotArea описывает, какие подписи данных показывать на диаграмме.
+type ChartPlotArea struct {
+	ShowBubbleSize bool `json:"show_bubble_size,omitempty"`
+	ShowCatName    bool `json:"show_cat_name,omitempty"`
+	ShowLegendKey  bool `json:"show_legend_key,omitempty"`
+	ShowPercent    bool `json:"show_percent,omitempty"`
+	ShowSerName    bool `json:"show_ser_name,omitempty"`
+	ShowVal        bool `json:"show_val,omitempty"`
+}
+
+// ChartAxis описывает ось диаграммы (XAxis/YAxis).
+type ChartAxis struct {
+	Title        string   `json:"title,omitempty"`
+	Minimum      *float64 `json:"minimum,omitempty"`
+	Maximum      *float64 `json:"maximum,omitempty"`
+	LogBase      float64  `json:"log_base,omitempty"`
+	ReverseOrder bool     `json:"reverse_order,omitempty"`
+	NumFmt       string   `json:"number_format,omitempty"`
+}
+
+// ChartTrendLine описывает линию тренда для одной серии.
+// Excelize v2.9.1 не предоставляет API для линий тренда, поэтому это поле
+// только сохраняет данные для будущего использования (и для симметричного
+// round-trip export -> import -> export), но пока не применяется.
+type ChartTrendLine struct {
+	Type   string `json:"type"` // linear, exp, log, movingAvg, poly, power
+	Series string `json:"series"`
+}
+
+// ChartSpec описывает одно семейство диаграмм в реестре: базовый
+// excelize.ChartType и его варианты stacked/percentStacked, если они есть.
+type ChartSpec struct {
+	Base            excelize.ChartType
+	Stacked         excelize.ChartType // 0, если у этого семейства нет stacked-варианта
+	PercentStacked  excelize.ChartType // 0, если у этого семейства нет percentStacked-варианта
+	DefaultGrouping string
+}
+
+// chartRegistry заменяет старый плоский switch: каждому типу из JSON
+// соответствует запись с базовым типом и (где применимо) его
+// stacked/percentStacked вариантами для Excelize v2.9.1.
+var chartRegistry = map[string]ChartSpec{
+	"col":            {Base: excelize.Col, Stacked: excelize.ColStacked, PercentStacked: excelize.ColPercentStacked, DefaultGrouping: "clustered"},
+	"col3D":          {Base: excelize.Col3D, Stacked: excelize.Col3DStacked, PercentStacked: excelize.Col3DPercentStacked, DefaultGrouping: "clustered"},
+	"col3DClustered": {Base: excelize.Col3DClustered, DefaultGrouping: "clustered"},
+	"bar":            {Base: excelize.Bar, Stacked: excelize.BarStacked, PercentStacked: excelize.BarPercentStacked, DefaultGrouping: "clustered"},
+	"line":           {Base: excelize.Line, DefaultGrouping: "standard"},
+	"line3D":         {Base: excelize.Line3D, DefaultGrouping: "standard"},
+	"area":           {Base: excelize.Area, Stacked: excelize.AreaStacked, PercentStacked: excelize.AreaPercentStacked, DefaultGrouping: "standard"},
+	"pie":            {Base: excelize.Pie, DefaultGrouping: "standard"},
+	"pie3D":          {Base: excelize.Pie3D, DefaultGrouping: "standard"},
+	"pieOfPie":       {Base: excelize.PieOfPie, DefaultGrouping: "standard"},
+	"barOfPie":       {Base: excelize.BarOfPie, DefaultGrouping: "standard"},
+	"doughnut":       {Base: excelize.Doughnut, DefaultGrouping: "standard"},
+	"radar":          {Base: excelize.Radar, DefaultGrouping: "standard"},
+	"scatter":        {Base: excelize.Scatter, DefaultGrouping: "standard"},
+	"stock":          {Base: excelize.StockHighLowClose, DefaultGrouping: "standard"},
+	"surface3D":      {Base: excelize.Surface3D, DefaultGrouping: "standard"},
+	"bubble":         {Base: excelize.Bubble, DefaultGrouping: "standard"},
+	"bubble3D":       {Base: excelize.Bubble3D, DefaultGrouping: "standard"},
+}
+
+// supportedChartTypeNames возвращает отсортированный список зарегистрированных
+// типов диаграмм — используется в предупреждении о неизвестном типе.
+func supportedChartTypeNames() []string {
+	names := make([]string, 0, len(chartRegistry))
+	for name := range chartRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveChartType выбирает конкретный excelize.ChartType для записи реестра
+// с учётом Grouping (stacked/percentStacked/clustered), запрошенного в JSON.
+func resolveChartType(chartTypeStr, grouping string) (excelize.ChartType, bool) {
+	spec, ok := chartRegistry[chartTypeStr]
+	if !ok {
+		return 0, false
+	}
+	if grouping == "" {
+		grouping = spec.DefaultGrouping
+	}
+	switch grouping {
+	case "stacked":
+		if spec.Stacked != 0 {
+			return spec.Stacked, true
+		}
+	case "percentStacked":
+		if spec.PercentStacked != 0 {
+			return spec.PercentStacked, true
+		}
+	}
+	return spec.Base, true
+}
+
+// builtinNumFmts отображает строковые коды числовых форматов openpyxl на
+// встроенные целочисленные коды Excel. Форматы, не попавшие в эту таблицу,
+// считаются пользовательскими и передаются через CustomNumFmt.
+var builtinNumFmts = map[string]int{
+	"General":       0,
+	"0":             1,
+	"0.00":          2,
+	"#,##0":         3,
+	"#,##0.00":      4,
+	"0%":            9,
+	"0.00%":         10,
+	"0.00E+00":      11,
+	"# ?/?":         12,
+	"# ??/??":       13,
+	"mm-dd-yy":      14,
+	"d-mmm-yy":      15,
+	"d-mmm":         16,
+	"mmm-yy":        17,
+	"h:mm AM/PM":    18,
+	"h:mm:ss AM/PM": 19,
+	"h:mm":          20,
+	"h:mm:ss":       21,
+	"m/d/yy h:mm":   22,
+	"@":             49,
+}
+
+// colorFromMap извлекает RGB-цвет вида "FF0000" из вложенного словаря
+// {"rgb": "FF0000"}, который присылает openpyxl.
+func colorFromMap(m map[string]interface{}) string {
+	if colorData, ok := m["color"].(map[string]interface{}); ok {
+		if rgb, ok := colorData["rgb"].(string); ok && rgb != "" {
+			return rgb
+		}
+	}
+	if rgb, ok := m["rgb"].(string); ok && rgb != "" {
+		return rgb
+	}
+	return ""
+}
+
+// borderStyleFromString отображает строковое имя стиля границы openpyxl
+// (thin, medium, thick, dashed, ...) на числовой код Excelize.
+func borderStyleFromString(styleStr string) int {
+	switch styleStr {
+	case "hair":
+		return 1
+	case "thin":
+		return 2
+	case "dashed":
+		return 3
+	case "dotted":
+		return 4
+	case "medium":
+		return 6
+	case "double":
+		return 7
+	case "thick":
+		return 8
+	case "mediumDashed":
+		return 9
+	default:
+		return 0
+	}
+}
+
+// convertAlignment переносит horizontal/vertical/wrap_text/text_rotation/indent
+// из словаря стиля в excelize.Alignment.
+func convertAlignment(data map[string]interface{}) *excelize.Alignment {
+	alignment := &excelize.Alignment{}
+	if horizontal, ok := data["horizontal"].(string); ok {
+		alignment.Horizontal = horizontal
+	}
+	if vertical, ok := data["vertical"].(string); ok {
+		alignment.Vertical = vertical
+	}
+	if wrapText, ok := data["wrap_text"].(bool); ok {
+		alignment.WrapText = wrapText
+	}
+	if rotation, ok := data["text_rotation"].(float64); ok {
+		alignment.TextRotation = int(rotation)
+	}
+	if indent, ok := data["indent"].(float64); ok {
+		alignment.Indent = int(indent)
+	}
+	return alignment
+}
+
+// convertBorder переносит top/bottom/left/right (и диагонали) из словаря
+// стиля в список excelize.Border.
+func convertBorder(data map[string]interface{}) []excelize.Border {
+	var borders []excelize.Border
+	for _, side := range []string{"left", "right", "top", "bottom", "diagonal"} {
+		sideData, ok := data[side].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		styleStr, _ := sideData["style"].(string)
+		if styleStr == "" {
+			continue
+		}
+		borders = append(borders, excelize.Border{
+			Type:  side,
+			Color: colorFromMap(sideData),
+			Style: borderStyleFromString(styleStr),
+		})
+	}
+	return borders
+}
+
+// convertFill переносит patternType/fgColor/bgColor (а также цвета всех точек
+// gradient.stops, если они заданы — тогда заливка становится градиентной) в excelize.Fill.
+func convertFill(data map[string]interface{}) excelize.Fill {
+	fill := excelize.Fill{Type: "pattern", Pattern: 1}
+	if patternType, ok := data["patternType"].(string); ok {
+		switch patternType {
+		case "solid":
+			fill.Pattern = 1
+		case "darkGray":
+			fill.Pattern = 2
+		case "mediumGray":
+			fill.Pattern = 3
+		case "lightGray":
+			fill.Pattern = 4
+		case "gray125":
+			fill.Pattern = 17
+		case "gray0625":
+			fill.Pattern = 18
+		default:
+			fill.Pattern = 0
+		}
+	}
+	if fgColor, ok := data["fgColor"].(map[string]interface{}); ok {
+		if rgb := colorFromMap(fgColor); rgb != "" {
+			fill.Color = []string{rgb}
+		}
+	}
+	if len(fill.Color) == 0 {
+		if bgColor, ok := data["bgColor"].(map[string]interface{}); ok {
+			if rgb := colorFromMap(bgColor); rgb != "" {
+				fill.Color = []string{rgb}
+			}
+		}
+	}
+	if gradient, ok := data["gradient"].(map[string]interface{}); ok {
+		if stops, ok := gradient["stops"].([]interface{}); ok {
+			fill.Type = "gradient"
+			fill.Shading = 0
+			for _, stop := range stops {
+				stopMap, ok := stop.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if rgb := colorFromMap(stopMap); rgb != "" {
+					fill.Color = append(fill.Color, rgb)
+				}
+			}
+		}
+	}
+	return fill
+}
+
+// convertNumberFormat переносит number_format в excelize.Style, используя
+// встроенный код там, где это возможно, и CustomNumFmt для остальных случаев.
+func convertNumberFormat(style map[string]interface{}, excelizeStyle *excelize.Style) {
+	numFmt, ok := style["number_format"].(string)
+	if !ok || numFmt == "" {
+		return
+	}
+	if code, found := builtinNumFmts[numFmt]; found {
+		excelizeStyle.NumFmt = code
+		return
+	}
+	custom := numFmt
+	excelizeStyle.CustomNumFmt = &custom
+}
+
+// convertProtection переносит locked/hidden из словаря стиля в excelize.Protection.
+func convertProtection(data map[string]interface{}) *excelize.Protection {
+	protection := &excelize.Protection{Locked: true}
+	if locked, ok := data["locked"].(bool); ok {
+		protection.Locked = locked
+	}
+	if hidden, ok := data["hidden"].(bool); ok {
+		protection.Hidden = hidden
+	}
+	return protection
+}
+
+// convertStyleToExcelizeStyle отображает словарь стиля (как его присылает
+// Python-сторона) в *excelize.Style: font, fill, border, number_format,
+// alignment и protection.
+func convertStyleToExcelizeStyle(style map[string]interface{}) *excelize.Style {
+	excelizeStyle := &excelize.Style{}
+
+	if fontData, ok := style["font"].(map[string]interface{}); ok {
+		font := &excelize.Font{}
+		if name, ok := fontData["name"].(string); ok {
+			font.Family = name
+		}
+		if size, ok := fontData["size"].(float64); ok {
+			font.Size = size
+		}
+		if bold, ok := fontData["bold"].(bool); ok {
+			font.Bold = bold
+		}
+		if italic, ok := fontData["italic"].(bool); ok {
+			font.Italic = italic
+		}
+		if underline, ok := fontData["underline"].(string); ok {
+			font.Underline = underline
+		}
+		if rgb := colorFromMap(fontData); rgb != "" {
+			font.Color = rgb
+		}
+		excelizeStyle.Font = font
+	}
+
+	if fillData, ok := style["fill"].(map[string]interface{}); ok {
+		excelizeStyle.Fill = convertFill(fillData)
+	}
+
+	if borderData, ok := style["border"].(map[string]interface{}); ok {
+		excelizeStyle.Border = convertBorder(borderData)
+	}
+
+	convertNumberFormat(style, excelizeStyle)
+
+	if alignmentData, ok := style["alignment"].(map[string]interface{}); ok {
+		excelizeStyle.Alignment = convertAlignment(alignmentData)
+	}
+
+	if protectionData, ok := style["protection"].(map[string]interface{}); ok {
+		excelizeStyle.Protection = convertProtection(protectionData)
+	}
+
+	return excelizeStyle
+}
+
+// styleCache кеширует идентификаторы стилей Excelize по каноническому
+// JSON-представлению входного словаря, чтобы не создавать заново одинаковые
+// стили для разных диапазонов.
+type styleCache struct {
+	f   *excelize.File
+	ids map[string]int
+}
+
+func newStyleCache(f *excelize.File) *styleCache {
+	return &styleCache{f: f, ids: make(map[string]int)}
+}
+
+// getOrCreate возвращает styleID для данного словаря стиля, создавая его
+// через f.NewStyle только при первом обращении к данной комбинации полей.
+func (c *styleCache) getOrCreate(style map[string]interface{}) (int, error) {
+	if style == nil {
+		return 0, nil
+	}
+	canonical, err := json.Marshal(style)
+	if err != nil {
+		return 0, fmt.Errorf("canonicalizing style: %w", err)
+	}
+	key := string(canonical)
+	if id, ok := c.ids[key]; ok {
+		return id, nil
+	}
+	id, err := c.f.NewStyle(convertStyleToExcelizeStyle(style))
+	if err != nil {
+		return 0, err
+	}
+	c.ids[key] = id
+	return id, nil
+}
+
+// applyConditionalFormat строит excelize.ConditionalFormatOptions из CondRule
+// и применяет правило к диапазону через f.SetConditionalFormat.
+func applyConditionalFormat(f *excelize.File, sheetName string, cache *styleCache, rule CondRule) error {
+	opt := excelize.ConditionalFormatOptions{}
+
+	if rule.Style != nil {
+		styleID, err := cache.getOrCreate(rule.Style)
+		if err != nil {
+			return fmt.Errorf("building style for conditional format: %w", err)
+		}
+		opt.Format = &styleID
+	}
+
+	switch rule.Type {
+	case "cellIs":
+		opt.Type = "cell"
+		opt.Criteria = rule.Criteria
+		opt.Value = rule.Value
+		if rule.Formula != "" {
+			opt.MaxValue = rule.Formula
+		}
+	case "colorScale":
+		opt.Type = "2_color_scale"
+		if rule.MidColor != "" {
+			opt.Type = "3_color_scale"
+		}
+		opt.MinType = "min"
+		opt.MinColor = rule.MinColor
+		if rule.MidColor != "" {
+			opt.MidType = "percentile"
+			opt.MidValue = "50"
+			opt.MidColor = rule.MidColor
+		}
+		opt.MaxType = "max"
+		opt.MaxColor = rule.MaxColor
+	case "dataBar":
+		opt.Type = "data_bar"
+		opt.MinType = "min"
+		opt.MaxType = "max"
+		opt.BarColor = rule.BarColor
+	case "top10":
+		opt.Type = "top"
+		if rule.Bottom {
+			opt.Type = "bottom"
+		}
+		if rule.Rank > 0 {
+			opt.Value = fmt.Sprintf("%d", rule.Rank)
+		} else {
+			opt.Value = "10"
+		}
+	case "duplicateValues":
+		opt.Type = "duplicate"
+	case "expression":
+		opt.Type = "formula"
+		opt.Criteria = rule.Formula
+	default:
+		return fmt.Errorf("unknown conditional format type %q", rule.Type)
+	}
+
+	return f.SetConditionalFormat(sheetName, rule.Range, []excelize.ConditionalFormatOptions{opt})
+}
+
+// expandRange раскрывает диапазон вида "A1:C3" (или одиночную ячейку "A1")
+// в список адресов всех входящих в него ячеек.
+func expandRange(rangeStr string) ([]string, error) {
+	parts := strings.Split(rangeStr, ":")
+	startCol, startRow, err := excelize.CellNameToCoordinates(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	endCol, endRow := startCol, startRow
+	if len(parts) == 2 {
+		endCol, endRow, err = excelize.CellNameToCoordinates(parts[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	var addrs []string
+	for row := startRow; row <= endRow; row++ {
+		for col := startCol; col <= endCol; col++ {
+			name, err := excelize.CoordinatesToCellName(col, row)
+			if err != nil {
+				return nil, err
+			}
+			addrs = append(addrs, name)
+		}
+	}
+	return addrs, nil
+}
+
+// buildCellStyleIndex разворачивает диапазоны sheet.Styles в индекс
+// "адрес ячейки -> styleID", нужный потоковому писателю, который не умеет
+// адресовать ячейки по диапазону. Более поздние записи в Styles
+// перекрывают более ранние, как и последовательные вызовы SetCellStyleByRange.
+func buildCellStyleIndex(styles *styleCache, sheetStyles []Style) map[string]int {
+	index := make(map[string]int)
+	for _, styleObj := range sheetStyles {
+		styleID, err := styles.getOrCreate(styleObj.Style)
+		if err != nil {
+			log.Printf("Warning: could not build style for range '%s': %v", styleObj.Range, err)
+			continue
+		}
+		addrs, err := expandRange(styleObj.Range)
+		if err != nil {
+			log.Printf("Warning: could not expand range '%s': %v", styleObj.Range, err)
+			continue
+		}
+		for _, addr := range addrs {
+			index[addr] = styleID
+		}
+	}
+	return index
+}
+
+// convertChartSeries переносит ChartSeries из JSON в excelize.ChartSeries.
+func convertChartSeries(series ChartSeries) excelize.ChartSeries {
+	out := excelize.ChartSeries{
+		Name:       series.Name,
+		Categories: series.Categories,
+		Values:     series.Values,
+	}
+	if series.Fill != "" {
+		out.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{series.Fill}}
+	}
+	if series.Line != nil {
+		out.Line = excelize.LineOptions{Smooth: series.Line.Smooth, Width: series.Line.Width}
+	}
+	if series.Marker != nil {
+		out.Marker = excelize.ChartMarker{Symbol: series.Marker.Symbol, Size: series.Marker.Size}
+	}
+	return out
+}
+
+// convertChartAxis переносит ChartAxis из JSON в excelize.ChartAxis.
+func convertChartAxis(axis *ChartAxis) excelize.ChartAxis {
+	out := excelize.ChartAxis{}
+	if axis == nil {
+		return out
+	}
+	if axis.Title != "" {
+		out.Title = excelize.ChartTitle{Paragraph: []excelize.RichTextRun{{Text: axis.Title}}}
+	}
+	if axis.Minimum != nil {
+		out.Minimum = *axis.Minimum
+	}
+	if axis.Maximum != nil {
+		out.Maximum = *axis.Maximum
+	}
+	out.LogBase = axis.LogBase
+	out.ReverseOrder = axis.ReverseOrder
+	out.NumFmt = axis.NumFmt
+	return out
+}
+
+// buildChartConfig строит excelize.Chart, а если какие-то серии используют
+// вторичную ось (SecondaryAxis), то ещё и второй excelize.Chart, который
+// передаётся в f.AddChart как combo-диаграмма.
+func buildChartConfig(chart Chart) (primary *excelize.Chart, combo *excelize.Chart) {
+	chartType, ok := resolveChartType(chart.Type, chart.Grouping)
+	if !ok {
+		log.Printf("Warning: Chart type '%s' is not supported, known types: %s. Using 'col' as default.",
+			chart.Type, strings.Join(supportedChartTypeNames(), ", "))
+		chartType = excelize.Col
+	}
+	if len(chart.TrendLine) > 0 {
+		log.Printf("Warning: chart at %s requests %d trend line(s), but Excelize v2.9.1 has no trend line API; ignoring.",
+			chart.Position, len(chart.TrendLine))
+	}
+	if chart.Overlap != nil || chart.GapWidth != nil {
+		log.Printf("Warning: chart at %s requests overlap/gap_width, but Excelize v2.9.1's Chart struct has no such fields; ignoring.", chart.Position)
+	}
+
+	primary = &excelize.Chart{
+		Type:   chartType,
+		Series: []excelize.ChartSeries{},
+		Title:  excelize.ChartTitle{Paragraph: []excelize.RichTextRun{{Text: chart.Title}}},
+		XAxis:  convertChartAxis(chart.XAxis),
+		YAxis:  convertChartAxis(chart.YAxis),
+	}
+	if chart.Legend != nil {
+		primary.Legend = excelize.ChartLegend{Position: chart.Legend.Position, ShowLegendKey: chart.Legend.ShowLegendKey}
+	}
+	if chart.PlotArea != nil {
+		primary.PlotArea = excelize.ChartPlotArea{
+			ShowBubbleSize: chart.PlotArea.ShowBubbleSize,
+			ShowCatName:    chart.PlotArea.ShowCatName,
+			ShowPercent:    chart.PlotArea.ShowPercent,
+			ShowSerName:    chart.PlotArea.ShowSerName,
+			ShowVal:        chart.PlotArea.ShowVal,
+		}
+	}
+	if chart.DataLabels {
+		primary.PlotArea.ShowVal = true
+	}
+
+	for i, series := range chart.Series {
+		converted := convertChartSeries(series)
+		onSecondary := i < len(chart.SecondaryAxis) && chart.SecondaryAxis[i]
+		if onSecondary {
+			if combo == nil {
+				combo = &excelize.Chart{
+					Type:   chartType,
+					Series: []excelize.ChartSeries{},
+					YAxis:  excelize.ChartAxis{Secondary: true},
+				}
+			}
+			combo.Series = append(combo.Series, converted)
+			continue
+		}
+		primary.Series = append(primary.Series, converted)
+	}
+
+	return primary, combo
+}
+
+// processSheetCharts добавляет диаграммы листа. Диаграммы хранятся отдельно
+// от потока ячеек, поэтому этот шаг одинаков для обоих движков и в режиме
+// stream выполняется уже после sw.Flush().
+func processSheetCharts(f *excelize.File, sheet SheetData) {
+	for _, chart := range sheet.Charts {
+		primary, combo := buildChartConfig(chart)
+
+		var err error
+		if combo != nil {
+			err = f.AddChart(sheet.Name, chart.Position, primary, combo)
+		} else {
+			err = f.AddChart(sheet.Name, chart.Position, primary)
+		}
+		if err != nil {
+			log.Printf("Warning: could not add chart at %s: %v", chart.Position, err)
+		}
+	}
+}
+
+// splitRange разбивает строку вида "A1:B2" на ["A1", "B2"].
+func splitRange(rangeStr string) []string {
+	parts := strings.SplitN(rangeStr, ":", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	return parts
+}
+
+// applySheetOptions применяет параметры листа, не относящиеся к самим
+// ячейкам: заморозку областей, видимость, цвет ярлыка, объединение ячеек,
+// ширину колонок/высоту строк, автофильтр, защиту и параметры страницы.
+func applySheetOptions(f *excelize.File, sheetName string, sheet SheetData) {
+	if sheet.FrozenPanes != nil && (sheet.FrozenPanes.Rows > 0 || sheet.FrozenPanes.Cols > 0) {
+		topLeftCell, _ := excelize.CoordinatesToCellName(sheet.FrozenPanes.Cols+1, sheet.FrozenPanes.Rows+1)
+		panes := &excelize.Panes{
+			Freeze:      true,
+			Split:       false,
+			XSplit:      sheet.FrozenPanes.Cols,
+			YSplit:      sheet.FrozenPanes.Rows,
+			TopLeftCell: topLeftCell,
+			ActivePane:  "bottomRight",
+		}
+		if err := f.SetPanes(sheetName, panes); err != nil {
+			log.Printf("Warning: could not set frozen panes on sheet '%s': %v", sheetName, err)
+		}
+	}
+
+	if sheet.VeryHidden {
+		if err := f.SetSheetVisible(sheetName, false, true); err != nil {
+			log.Printf("Warning: could not set sheet '%s' very hidden: %v", sheetName, err)
+		}
+	} else if sheet.Hidden {
+		if err := f.SetSheetVisible(sheetName, false); err != nil {
+			log.Printf("Warning: could not hide sheet '%s': %v", sheetName, err)
+		}
+	}
+
+	if sheet.TabColor != "" {
+		if err := f.SetSheetProps(sheetName, &excelize.SheetPropsOptions{TabColorRGB: &sheet.TabColor}); err != nil {
+			log.Printf("Warning: could not set tab color on sheet '%s': %v", sheetName, err)
+		}
+	}
+
+	for _, mergedRange := range sheet.MergedCells {
+		coords := splitRange(mergedRange)
+		if len(coords) != 2 {
+			log.Printf("Warning: invalid merged cell range '%s' on sheet '%s', skipping", mergedRange, sheetName)
+			continue
+		}
+		if err := f.MergeCell(sheetName, coords[0], coords[1]); err != nil {
+			log.Printf("Warning: could not merge cells '%s' on sheet '%s': %v", mergedRange, sheetName, err)
+		}
+	}
+
+	for col, width := range sheet.ColumnWidths {
+		if err := f.SetColWidth(sheetName, col, col, width); err != nil {
+			log.Printf("Warning: could not set width of column '%s' on sheet '%s': %v", col, sheetName, err)
+		}
+	}
+
+	for rowStr, height := range sheet.RowHeights {
+		row, err := strconv.Atoi(rowStr)
+		if err != nil {
+			log.Printf("Warning: invalid row number '%s' in row_heights on sheet '%s', skipping", rowStr, sheetName)
+			continue
+		}
+		if err := f.SetRowHeight(sheetName, row, height); err != nil {
+			log.Printf("Warning: could not set height of row %d on sheet '%s': %v", row, sheetName, err)
+		}
+	}
+
+	if sheet.AutoFilter != "" {
+		if err := f.AutoFilter(sheetName, sheet.AutoFilter, nil); err != nil {
+			log.Printf("Warning: could not set auto filter '%s' on sheet '%s': %v", sheet.AutoFilter, sheetName, err)
+		}
+	}
+
+	if sheet.Protection != nil {
+		opts := &excelize.SheetProtectionOptions{
+			Password:          sheet.Protection.Password,
+			SelectLockedCells: !sheet.Protection.AllowSelectLocked,
+			FormatCells:       !sheet.Protection.AllowFormatCells,
+			Sort:              !sheet.Protection.AllowSort,
+			AutoFilter:        !sheet.Protection.AllowAutoFilter,
+			InsertRows:        !sheet.Protection.AllowInsertRows,
+			DeleteRows:        !sheet.Protection.AllowDeleteRows,
+		}
+		if err := f.ProtectSheet(sheetName, opts); err != nil {
+			log.Printf("Warning: could not protect sheet '%s': %v", sheetName, err)
+		}
+	}
+
+	if sheet.PageSetup != nil {
+		layout := &excelize.PageLayoutOptions{}
+		if sheet.PageSetup.Orientation != "" {
+			orientation := sheet.PageSetup.Orientation
+			layout.Orientation = &orientation
+		}
+		if sheet.PageSetup.PaperSize != 0 {
+			size := sheet.PageSetup.PaperSize
+			layout.Size = &size
+		}
+		if err := f.SetPageLayout(sheetName, layout); err != nil {
+			log.Printf("Warning: could not set page layout on sheet '%s': %v", sheetName, err)
+		}
+		if sheet.PageSetup.Header != "" || sheet.PageSetup.Footer != "" {
+			if err := f.SetHeaderFooter(sheetName, &excelize.HeaderFooterOptions{
+				OddHeader: sheet.PageSetup.Header,
+				OddFooter: sheet.PageSetup.Footer,
+			}); err != nil {
+				log.Printf("Warning: could not set header/footer on sheet '%s': %v", sheetName, err)
+			}
+		}
+		if sheet.PageSetup.PrintArea != "" {
+			if err := f.SetDefinedName(&excelize.DefinedName{
+				Name:     "_xlnm.Print_Area",
+				RefersTo: fmt.Sprintf("%s!%s", sheetName, sheet.PageSetup.PrintArea),
+				Scope:    sheetName,
+			}); err != nil {
+				log.Printf("Warning: could not set print area on sheet '%s': %v", sheetName, err)
+			}
+		}
+	}
+}
+
+// dataValidationOperator отображает строковый оператор на excelize.DataValidationOperator.
+func dataValidationOperator(operator string) excelize.DataValidationOperator {
+	switch operator {
+	case "notBetween":
+		return excelize.DataValidationOperatorNotBetween
+	case "equal":
+		return excelize.DataValidationOperatorEqual
+	case "notEqual":
+		return excelize.DataValidationOperatorNotEqual
+	case "greaterThan":
+		return excelize.DataValidationOperatorGreaterThan
+	case "greaterThanOrEqual":
+		return excelize.DataValidationOperatorGreaterThanOrEqual
+	case "lessThan":
+		return excelize.DataValidationOperatorLessThan
+	case "lessThanOrEqual":
+		return excelize.DataValidationOperatorLessThanOrEqual
 	default:
-		// Возвращаем тип по умолчанию, если тип не распознан
-		fmt.Printf("Warning: Unknown chart type '%s', using 'col' as default.\n", chartTypeStr)
-		return excelize.Col // или другой тип по умолчанию
+		return excelize.DataValidationOperatorBetween
 	}
 }
 
+// applyDataValidations переносит sheet.Validations в excelize через
+// excelize.NewDataValidation + f.AddDataValidation.
+func applyDataValidations(f *excelize.File, sheetName string, validations []DataValidation) {
+	for _, v := range validations {
+		dv := excelize.NewDataValidation(v.AllowBlank)
+		dv.Sqref = v.Range
+
+		var err error
+		switch v.Type {
+		case "list":
+			err = dv.SetDropList(strings.Split(v.Formula1, ","))
+		case "whole":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeWhole, dataValidationOperator(v.Operator))
+		case "decimal":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeDecimal, dataValidationOperator(v.Operator))
+		case "date":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeDate, dataValidationOperator(v.Operator))
+		case "textLength":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeTextLength, dataValidationOperator(v.Operator))
+		case "custom":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeCustom, dataValidationOperator(v.Operator))
+		default:
+			log.Printf("Warning: unknown data validation type '%s' for range '%s' on sheet '%s', skipping", v.Type, v.Range, sheetName)
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: could not build data validation for range '%s' on sheet '%s': %v", v.Range, sheetName, err)
+			continue
+		}
+
+		if v.InputTitle != "" || v.InputMessage != "" {
+			dv.SetInput(v.InputTitle, v.InputMessage)
+		}
+		if v.ErrorTitle != "" || v.ErrorMessage != "" {
+			dv.SetError(excelize.DataValidationErrorStyle(v.ErrorStyle), v.ErrorTitle, v.ErrorMessage)
+		}
+
+		if err := f.AddDataValidation(sheetName, dv); err != nil {
+			log.Printf("Warning: could not add data validation for range '%s' on sheet '%s': %v", v.Range, sheetName, err)
+		}
+	}
+}
+
+// applyHyperlinks переносит sheet.Hyperlinks через f.SetCellHyperLink.
+func applyHyperlinks(f *excelize.File, sheetName string, hyperlinks []Hyperlink) {
+	for _, link := range hyperlinks {
+		linkType := link.Type
+		if linkType == "" {
+			linkType = "External"
+		}
+		opts := make([]excelize.HyperlinkOpts, 0, 2)
+		if link.Display != "" {
+			opts = append(opts, excelize.HyperlinkOpts{Display: &link.Display})
+		}
+		if link.Tooltip != "" {
+			opts = append(opts, excelize.HyperlinkOpts{Tooltip: &link.Tooltip})
+		}
+		if err := f.SetCellHyperLink(sheetName, link.Cell, link.Target, linkType, opts...); err != nil {
+			log.Printf("Warning: could not set hyperlink on cell '%s' of sheet '%s': %v", link.Cell, sheetName, err)
+		}
+	}
+}
+
+// applyComments переносит sheet.Comments через f.AddComment/f.AddThreadedComment.
+func applyComments(f *excelize.File, sheetName string, comments []Comment) {
+	for _, c := range comments {
+		richText := []excelize.RichTextRun{{Text: c.Text}}
+		var err error
+		if c.Threaded {
+			err = f.AddThreadedComment(sheetName, &excelize.ThreadedComment{
+				Cell:     c.Cell,
+				Author:   c.Author,
+				Text:     c.Text,
+				Comments: []excelize.Comment{},
+			})
+		} else {
+			err = f.AddComment(sheetName, excelize.Comment{
+				Cell:      c.Cell,
+				Author:    c.Author,
+				Text:      c.Text,
+				Paragraph: richText,
+			})
+		}
+		if err != nil {
+			log.Printf("Warning: could not add comment to cell '%s' of sheet '%s': %v", c.Cell, sheetName, err)
+		}
+	}
+}
+
+// applyImages переносит sheet.Images через f.AddPictureFromBytes.
+func applyImages(f *excelize.File, sheetName string, images []Image) {
+	for _, img := range images {
+		raw, err := base64.StdEncoding.DecodeString(img.Data)
+		if err != nil {
+			log.Printf("Warning: could not decode image for cell '%s' of sheet '%s': %v", img.Cell, sheetName, err)
+			continue
+		}
+		opts := &excelize.GraphicOptions{
+			ScaleX:  img.ScaleX,
+			ScaleY:  img.ScaleY,
+			OffsetX: img.OffsetX,
+			OffsetY: img.OffsetY,
+		}
+		if err := f.AddPictureFromBytes(sheetName, img.Cell, &excelize.Picture{
+			Extension: img.Extension,
+			File:      raw,
+			Format:    opts,
+		}); err != nil {
+			log.Printf("Warning: could not add image to cell '%s' of sheet '%s': %v", img.Cell, sheetName, err)
+		}
+	}
+}
+
+// applyDefinedNames регистрирует именованные диапазоны верхнего уровня
+// ExportData.DefinedNames. Если у DefinedName указан Sheet, имя ограничено
+// областью видимости этого листа, иначе оно видно во всей книге.
+func applyDefinedNames(f *excelize.File, names []DefinedName) {
+	for _, dn := range names {
+		if err := f.SetDefinedName(&excelize.DefinedName{
+			Name:     dn.Name,
+			RefersTo: dn.RefersTo,
+			Scope:    dn.Sheet,
+		}); err != nil {
+			log.Printf("Warning: could not set defined name '%s': %v", dn.Name, err)
+		}
+	}
+}
+
+// processSheetMemory — движок по умолчанию: заполняет лист через произвольный
+// доступ к ячейкам (SetCellValue/SetCellStyleByRange). Поддерживает все
+// возможности (стили диапазонами, условное форматирование), но держит
+// весь лист в памяти, что неприемлемо для очень больших выгрузок.
+func processSheetMemory(f *excelize.File, sheet SheetData, styles *styleCache) {
+	// Заполнение данными
+	for rowIndex, row := range sheet.Data {
+		cellRow := rowIndex + 1
+		for colIndex, cellValue := range row {
+			cellCol := colIndex + 1
+			cellName, _ := excelize.ColumnNumberToName(cellCol)
+			cellName += fmt.Sprintf("%d", cellRow)
+
+			if cellValue != nil {
+				f.SetCellValue(sheet.Name, cellName, *cellValue)
+			}
+		}
+	}
+
+	// Добавление формул
+	for _, formula := range sheet.Formulas {
+		f.SetCellFormula(sheet.Name, formula.Cell, formula.Formula)
+	}
+
+	// Применение стилей: каждый диапазон получает (при необходимости
+	// заново созданный, иначе переиспользованный из кеша) styleID.
+	for _, styleObj := range sheet.Styles {
+		styleID, err := styles.getOrCreate(styleObj.Style)
+		if err != nil {
+			log.Printf("Warning: could not build style for range '%s' on sheet '%s': %v", styleObj.Range, sheet.Name, err)
+			continue
+		}
+		if err := f.SetCellStyleByRange(sheet.Name, styleObj.Range, styleID); err != nil {
+			log.Printf("Warning: could not apply style to range '%s' on sheet '%s': %v", styleObj.Range, sheet.Name, err)
+		}
+	}
+
+	// Условное форматирование
+	for _, rule := range sheet.ConditionalFormats {
+		if err := applyConditionalFormat(f, sheet.Name, styles, rule); err != nil {
+			log.Printf("Warning: could not apply conditional format to range '%s' on sheet '%s': %v", rule.Range, sheet.Name, err)
+		}
+	}
+}
+
+// processSheetStream — движок для больших книг: пишет строки через
+// excelize.StreamWriter вместо произвольного доступа к ячейкам, что держит
+// в памяти только одну строку за раз. Ограничение потокового писателя:
+// строки должны уходить в порядке возрастания номера и после sw.Flush()
+// ячейки этого листа больше нельзя изменять — поэтому стили и формулы
+// собираются по адресу ещё до первой записи, а диаграммы добавляются
+// отдельным проходом после Flush. Условное форматирование по диапазону
+// применяется так же, как в режиме memory, так как оно не зависит от
+// потокового писателя.
+func processSheetStream(f *excelize.File, sheet SheetData, styles *styleCache) error {
+	sw, err := f.NewStreamWriter(sheet.Name)
+	if err != nil {
+		return fmt.Errorf("creating stream writer: %w", err)
+	}
+
+	cellStyles := buildCellStyleIndex(styles, sheet.Styles)
+
+	formulaByCell := make(map[string]string, len(sheet.Formulas))
+	for _, formula := range sheet.Formulas {
+		formulaByCell[formula.Cell] = formula.Formula
+	}
+
+	for rowIndex, row := range sheet.Data {
+		cellRow := rowIndex + 1
+		rowCells := make([]interface{}, len(row))
+		for colIndex, cellValue := range row {
+			cellCol := colIndex + 1
+			cellName, _ := excelize.ColumnNumberToName(cellCol)
+			cellName += fmt.Sprintf("%d", cellRow)
+
+			cell := excelize.Cell{StyleID: cellStyles[cellName]}
+			if cellValue != nil {
+				cell.Value = *cellValue
+			}
+			if formula, ok := formulaByCell[cellName]; ok {
+				cell.Formula = formula
+			}
+			rowCells[colIndex] = cell
+		}
+		axis, _ := excelize.CoordinatesToCellName(1, cellRow)
+		if err := sw.SetRow(axis, rowCells); err != nil {
+			return fmt.Errorf("writing row %d: %w", cellRow, err)
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("flushing stream writer: %w", err)
+	}
+
+	// Условное форматирование применяется после Flush: оно не трогает
+	// отдельные ячейки через StreamWriter, а работает через отдельный XML.
+	for _, rule := range sheet.ConditionalFormats {
+		if err := applyConditionalFormat(f, sheet.Name, styles, rule); err != nil {
+			log.Printf("Warning: could not apply conditional format to range '%s' on sheet '%s': %v", rule.Range, sheet.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// parseMetaHeader читает первые строки листа как мета-заголовок: первая
+// строка — ключи колонок, вторая (если есть) — их типы (int, float, bool,
+// date, string, json), третья (если есть) — явный порядковый индекс.
+// Отсутствующие строки означают значения по умолчанию (string, порядок слева направо).
+func parseMetaHeader(headerRows [][]string) []ColumnMeta {
+	if len(headerRows) == 0 {
+		return nil
+	}
+	keys := headerRows[0]
+	columns := make([]ColumnMeta, len(keys))
+	for i, key := range keys {
+		columns[i] = ColumnMeta{Key: key, Type: "string", Index: i}
+	}
+	if len(headerRows) > 1 {
+		for i, typ := range headerRows[1] {
+			if i < len(columns) && typ != "" {
+				columns[i].Type = typ
+			}
+		}
+	}
+	if len(headerRows) > 2 {
+		for i, idxStr := range headerRows[2] {
+			if i >= len(columns) || idxStr == "" {
+				continue
+			}
+			if idx, err := strconv.Atoi(idxStr); err == nil {
+				columns[i].Index = idx
+			}
+		}
+	}
+	return columns
+}
+
+// typedCellValue приводит строковое значение ячейки к типу, заявленному
+// в ColumnMeta.Type. Ошибки приведения не фатальны: значение возвращается
+// как есть в виде строки, чтобы импорт не прерывался из-за одной плохой ячейки.
+func typedCellValue(raw string, typ string) interface{} {
+	if raw == "" {
+		return nil
+	}
+	switch typ {
+	case "int":
+		if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return v
+		}
+	case "float":
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	case "bool":
+		if v, err := strconv.ParseBool(raw); err == nil {
+			return v
+		}
+	case "date":
+		for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01-02 15:04:05"} {
+			if v, err := time.Parse(layout, raw); err == nil {
+				return v.Format(time.RFC3339)
+			}
+		}
+	case "json":
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err == nil {
+			return v
+		}
+	}
+	return raw
+}
+
+// buildTypedRow превращает "сырую" строку листа в map[key]value, приводя
+// каждое значение к типу соответствующей колонки.
+func buildTypedRow(row []string, columns []ColumnMeta) map[string]interface{} {
+	typed := make(map[string]interface{}, len(columns))
+	for i, col := range columns {
+		if i >= len(row) {
+			typed[col.Key] = nil
+			continue
+		}
+		typed[col.Key] = typedCellValue(row[i], col.Type)
+	}
+	return typed
+}
+
+// runImport открывает существующий XLSX и сериализует его обратно в ту же
+// JSON-схему ExportData, которую потребляет режим экспорта. Это делает
+// инструмент двунаправленным и позволяет строить diff/merge поверх XLSX.
+// Если metaHeaderRows > 0, первые строки каждого листа интерпретируются как
+// мета-заголовок (см. parseMetaHeader) и переносятся в ProjectMetadata,
+// а данные листа дополнительно приводятся к типам в TypedRows.
+func runImport(inputPath, outputPath string, metaHeaderRows int) error {
+	f, err := excelize.OpenFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("opening xlsx: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	exportData := ExportData{
+		Metadata: ProjectMetadata{ProjectName: strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))},
+	}
+
+	for _, sheetName := range f.GetSheetList() {
+		rows, err := f.GetRows(sheetName)
+		if err != nil {
+			return fmt.Errorf("reading rows of sheet '%s': %w", sheetName, err)
+		}
+
+		sheet := SheetData{Name: sheetName}
+		dataRows := rows
+		if metaHeaderRows > 0 && len(rows) >= metaHeaderRows {
+			columns := parseMetaHeader(rows[:metaHeaderRows])
+			exportData.Metadata.Columns = columns
+			dataRows = rows[metaHeaderRows:]
+			for _, row := range dataRows {
+				sheet.TypedRows = append(sheet.TypedRows, buildTypedRow(row, columns))
+			}
+		}
+
+		for _, row := range dataRows {
+			cells := make([]*string, len(row))
+			for i, value := range row {
+				v := value
+				cells[i] = &v
+			}
+			sheet.Data = append(sheet.Data, cells)
+		}
+
+		for rowIndex, row := range rows {
+			for colIndex := range row {
+				cellName, err := excelize.CoordinatesToCellName(colIndex+1, rowIndex+1)
+				if err != nil {
+					continue
+				}
+				formula, err := f.GetCellFormula(sheetName, cellName)
+				if err != nil || formula == "" {
+					continue
+				}
+				sheet.Formulas = append(sheet.Formulas, Formula{Cell: cellName, Formula: formula})
+			}
+		}
+
+		// TODO: реконструировать Styles/Charts/ConditionalFormats при импорте —
+		// для этого нужен доступ к styleID по ячейке (excelize.File.GetCellStyle),
+		// который пока не используется здесь.
+
+		exportData.Sheets = append(exportData.Sheets, sheet)
+	}
+
+	jsonBytes, err := json.MarshalIndent(exportData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling export data: %w", err)
+	}
+	if err := os.WriteFile(outputPath, jsonBytes, 0644); err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	return nil
+}
+
 func main() {
 	// Парсинг аргументов командной строки
-	inputFile := flag.String("input", "", "Path to the input JSON file")
-	outputFile := flag.String("output", "", "Path to the output XLSX file")
+	inputFile := flag.String("input", "", "Path to the input JSON file (export mode) or XLSX file (import mode)")
+	outputFile := flag.String("output", "", "Path to the output XLSX file (export mode) or JSON file (import mode)")
+	modeFlag := flag.String("mode", "export", "\"export\" (JSON -> XLSX, default) or \"import\" (XLSX -> JSON, round-trip)")
+	metaHeaderRows := flag.Int("meta-header-rows", 0, "Import mode only: number of leading rows per sheet to treat as a "+
+		"column key/type/index header instead of data (see parseMetaHeader)")
+	engine := flag.String("engine", "auto", "Export engine: \"memory\" (random-access; what auto uses up to -stream-threshold-bytes), "+
+		"\"stream\" (excelize.StreamWriter, bounded memory but no random-access cell writes once a sheet is flushed — "+
+		"formulas/styles are resolved per-cell up front and charts are added in a second pass after Flush), "+
+		"or \"auto\" (pick stream when the input JSON exceeds -stream-threshold-bytes)")
+	streamThreshold := flag.Int64("stream-threshold-bytes", 10*1024*1024, "Input size above which -engine=auto switches to the stream engine")
 	flag.Parse()
 
 	if *inputFile == "" || *outputFile == "" {
-		fmt.Println("Usage: go_excel_exporter -input <input.json> -output <output.xlsx>")
+		fmt.Println("Usage: go_excel_exporter -input <input.json> -output <output.xlsx> [-engine memory|stream|auto]")
+		fmt.Println("       go_excel_exporter -mode import -input <input.xlsx> -output <output.json> [-meta-header-rows N]")
 		os.Exit(1)
 	}
 
+	if *modeFlag == "import" {
+		if err := runImport(*inputFile, *outputFile, *metaHeaderRows); err != nil {
+			log.Fatalf("Error importing xlsx: %v", err)
+		}
+		fmt.Printf("Successfully imported %s to %s\n", *inputFile, *outputFile)
+		return
+	}
+
 	// Чтение JSON-файла
 	jsonData, err := os.ReadFile(*inputFile)
 	if err != nil {
@@ -126,6 +1416,8 @@ func main() {
 		log.Fatalf("Error parsing JSON: %v", err)
 	}
 
+	useStream := *engine == "stream" || (*engine == "auto" && int64(len(jsonData)) > *streamThreshold)
+
 	// Создание нового Excel-файла
 	f := excelize.NewFile()
 	defer func() {
@@ -134,60 +1426,49 @@ func main() {
 		}
 	}()
 
-	// Обработка каждого листа
-	for _, sheet := range exportData.Sheets {
-		// Создание нового листа
-		if err := f.SetSheetName(f.GetSheetName(0), sheet.Name); err != nil {
-			log.Printf("Warning: could not rename first sheet to '%s': %v", sheet.Name, err)
-		}
+	styles := newStyleCache(f)
 
-		// Заполнение данными
-		for rowIndex, row := range sheet.Data {
-			cellRow := rowIndex + 1
-			for colIndex, cellValue := range row {
-				cellCol := colIndex + 1
-				cellName, _ := excelize.ColumnNumberToName(cellCol)
-				cellName += fmt.Sprintf("%d", cellRow)
-
-				if cellValue != nil {
-					f.SetCellValue(sheet.Name, cellName, *cellValue)
-				}
+	// Обработка каждого листа. Только первый лист переиспользует дефолтный
+	// лист, созданный excelize.NewFile() — остальные должны быть созданы
+	// через f.NewSheet, иначе каждая следующая итерация переименовывала бы
+	// один и тот же первый лист и все данные затирали бы друг друга.
+	for i, sheet := range exportData.Sheets {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), sheet.Name); err != nil {
+				log.Printf("Warning: could not rename first sheet to '%s': %v", sheet.Name, err)
 			}
+		} else if _, err := f.NewSheet(sheet.Name); err != nil {
+			log.Printf("Warning: could not create sheet '%s': %v", sheet.Name, err)
+			continue
 		}
 
-		// Добавление формул
-		for _, formula := range sheet.Formulas {
-			f.SetCellFormula(sheet.Name, formula.Cell, formula.Formula)
+		if useStream {
+			if err := processSheetStream(f, sheet, styles); err != nil {
+				log.Printf("Warning: stream export failed for sheet '%s', falling back to memory engine: %v", sheet.Name, err)
+				processSheetMemory(f, sheet, styles)
+			}
+		} else {
+			processSheetMemory(f, sheet, styles)
 		}
 
-		// TODO: Реализовать применение стилей
-		// Это будет самая сложная часть, так как нужно отобразить
-		// структуру стилей из Python в формат Excelize.
+		processSheetCharts(f, sheet)
+		applySheetOptions(f, sheet.Name, sheet)
+		applyDataValidations(f, sheet.Name, sheet.Validations)
+		applyHyperlinks(f, sheet.Name, sheet.Hyperlinks)
+		applyComments(f, sheet.Name, sheet.Comments)
+		applyImages(f, sheet.Name, sheet.Images)
+	}
 
-		// Добавление диаграмм
-		for _, chart := range sheet.Charts {
-			chartConfig := &excelize.Chart{
-				Type: convertChartType(chart.Type), // <-- Изменённая строка
-				Series: []excelize.ChartSeries{},
-				Title:  []excelize.RichTextRun{{Text: chart.Title}},
-			}
+	applyDefinedNames(f, exportData.DefinedNames)
 
-			for _, series := range chart.Series {
-				chartConfig.Series = append(chartConfig.Series, excelize.ChartSeries{
-					Name:       series.Name,
-					Categories: series.Categories,
-					Values:     series.Values,
-				})
-			}
-
-			if err := f.AddChart(sheet.Name, chart.Position, chartConfig); err != nil {
-				log.Printf("Warning: could not add chart at %s: %v", chart.Position, err)
-			}
+	if len(exportData.Sheets) > 0 {
+		activeIdx := exportData.Metadata.ActiveSheet
+		if activeIdx < 0 || activeIdx >= len(exportData.Sheets) {
+			activeIdx = 0
+		}
+		if idx, err := f.GetSheetIndex(exportData.Sheets[activeIdx].Name); err == nil {
+			f.SetActiveSheet(idx)
 		}
-
-		// Если есть ещё листы, создадим их
-		// (Первый лист уже существует по умолчанию)
-		// ... (логика для создания дополнительных листов)
 	}
 
 	// Сохранение файла
@@ -196,4 +1477,4 @@ func main() {
 	}
 
 	fmt.Printf("Successfully exported to %s\n", *outputFile)
-}
\ No newline at end of file
+}
@@ -8,11 +8,14 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/xuri/excelize/v2"
 )
@@ -32,12 +35,87 @@ type ProjectMetadata struct {
 
 // SheetData holds the data for a single worksheet.
 type SheetData struct {
-	Name         string       `json:"name"`
-	Data         [][]*string  `json:"data"` // nil represents empty cells
-	Formulas     []Formula    `json:"formulas,omitempty"`
-	Styles       []Style      `json:"styles,omitempty"`
-	Charts       []Chart      `json:"charts,omitempty"`
-	MergedCells  []string     `json:"merged_cells,omitempty"`
+	Name        string      `json:"name"`
+	Data        [][]*string `json:"data"` // nil represents empty cells
+	Formulas    []Formula   `json:"formulas,omitempty"`
+	Styles      []Style     `json:"styles,omitempty"`
+	Charts      []Chart     `json:"charts,omitempty"`
+	MergedCells []string    `json:"merged_cells,omitempty"`
+
+	ConditionalFormats []CondFormat `json:"conditional_formats,omitempty"`
+	DataValidations    []Validation `json:"data_validations,omitempty"`
+	Tables             []Table      `json:"tables,omitempty"`
+	Hyperlinks         []Hyperlink  `json:"hyperlinks,omitempty"`
+	Images             []Image      `json:"images,omitempty"`
+}
+
+// CondFormat represents one conditional formatting rule for a range, mirroring
+// excelize's ConditionalFormatOptions. Type is one of "cellIs", "expression",
+// "colorScale", "dataBar", "iconSet", "top10", or "duplicateValues". Style is an
+// optional reusable style dict (same shape as Style.Style) applied through
+// convertStyleToExcelizeOptions for types that highlight matching cells.
+type CondFormat struct {
+	Range        string                 `json:"range"`
+	Type         string                 `json:"type"`
+	Criteria     string                 `json:"criteria,omitempty"`
+	Value        string                 `json:"value,omitempty"`
+	Formula      string                 `json:"formula,omitempty"`
+	MinColor     string                 `json:"min_color,omitempty"`
+	MidColor     string                 `json:"mid_color,omitempty"`
+	MaxColor     string                 `json:"max_color,omitempty"`
+	BarColor     string                 `json:"bar_color,omitempty"`
+	IconStyle    string                 `json:"icon_style,omitempty"`
+	ReverseIcons bool                   `json:"reverse_icons,omitempty"`
+	Rank         int                    `json:"rank,omitempty"`
+	Bottom       bool                   `json:"bottom,omitempty"`
+	Style        map[string]interface{} `json:"style,omitempty"`
+}
+
+// Validation represents a data validation rule for a range. Type is one of
+// "list", "whole", "decimal", "date", "time", "textLength", or "custom".
+// Operator is ignored for "list" and "custom".
+type Validation struct {
+	Range        string `json:"range"`
+	Type         string `json:"type"`
+	Operator     string `json:"operator,omitempty"`
+	Formula1     string `json:"formula1,omitempty"`
+	Formula2     string `json:"formula2,omitempty"`
+	AllowBlank   bool   `json:"allow_blank,omitempty"`
+	InputTitle   string `json:"input_title,omitempty"`
+	InputMessage string `json:"input_message,omitempty"`
+	ErrorStyle   string `json:"error_style,omitempty"` // "stop", "warning", "information"
+	ErrorTitle   string `json:"error_title,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// Table represents an Excel table (ListObject) over a range.
+type Table struct {
+	Range          string `json:"range"`
+	Name           string `json:"name,omitempty"`
+	StyleName      string `json:"style_name,omitempty"`
+	ShowRowStripes bool   `json:"show_row_stripes,omitempty"`
+}
+
+// Hyperlink represents a hyperlink on a single cell. Type is "External" (a
+// regular URL) or "Location" (a link to a range within the workbook).
+type Hyperlink struct {
+	Cell    string `json:"cell"`
+	Target  string `json:"target"`
+	Type    string `json:"type,omitempty"`
+	Tooltip string `json:"tooltip,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// Image represents a picture anchored at a cell. Exactly one of Path (a
+// filesystem path, added via f.AddPicture) or Data (base64-encoded file
+// content, added via f.AddPictureFromBytes) should be set.
+type Image struct {
+	Cell      string  `json:"cell"`
+	Path      string  `json:"path,omitempty"`
+	Data      string  `json:"data,omitempty"`
+	Extension string  `json:"extension,omitempty"` // required when Data is set, e.g. ".png"
+	ScaleX    float64 `json:"scale_x,omitempty"`
+	ScaleY    float64 `json:"scale_y,omitempty"`
 }
 
 // Formula represents a cell formula.
@@ -54,10 +132,19 @@ type Style struct {
 
 // Chart represents a chart definition.
 type Chart struct {
-	Type     string        `json:"type"`
-	Position string        `json:"position"`
-	Title    string        `json:"title,omitempty"`
-	Series   []ChartSeries `json:"series"`
+	Type           string        `json:"type"`
+	Position       string        `json:"position"`
+	Title          string        `json:"title,omitempty"`
+	Series         []ChartSeries `json:"series"`
+	XAxisTitle     string        `json:"x_axis_title,omitempty"`
+	YAxisTitle     string        `json:"y_axis_title,omitempty"`
+	Legend         string        `json:"legend,omitempty"` // "top", "bottom", "left", "right", "top_right", "none"
+	ShowDataLabels bool          `json:"show_data_labels,omitempty"`
+	Width          uint          `json:"width,omitempty"`
+	Height         uint          `json:"height,omitempty"`
+	// Combo holds additional charts plotted on the same position, typically on a
+	// secondary axis (e.g. a line chart overlaid on a column chart).
+	Combo []Chart `json:"combo,omitempty"`
 }
 
 // ChartSeries represents a data series for a chart.
@@ -66,38 +153,66 @@ type ChartSeries struct {
 	Categories string `json:"categories"`
 	Values     string `json:"values"`
 }
-// convertChartType converts a string chart type from JSON to excelize.ChartType.
-// It supports basic types available in Excelize v2.9.1.
-// Unsupported or unknown types default to 'Col'.
+
+// supportedChartTypeNames lists every chart type string convertChartType maps to
+// a real excelize.ChartType, in the order checked. Kept in sync with the switch
+// below so the "unknown type" warning can tell the caller what is actually valid.
+var supportedChartTypeNames = []string{
+	"col", "colStacked", "colPercentStacked", "col3D", "col3DClustered", "col3DStacked", "col3DPercentStacked",
+	"line", "line3D",
+	"pie", "pie3D", "pieOfPie", "doughnut",
+	"bar", "barOfPie", "area", "radar", "surface3D",
+	"scatter",
+	"bubble", "bubble3D",
+}
+
+// convertChartType converts a string chart type from JSON to excelize.ChartType,
+// covering the stacked, 3D, and combo-friendly variants excelize v2 exposes.
+// Unknown types fall back to 'Col' and log a warning listing the supported names.
 func convertChartType(chartTypeStr string) excelize.ChartType {
 	switch chartTypeStr {
-	// Supported types in v2.9.1
 	case "col":
 		return excelize.Col
+	case "colStacked":
+		return excelize.ColStacked
+	case "colPercentStacked":
+		return excelize.ColPercentStacked
+	case "col3D", "col3DClustered":
+		return excelize.Col3DClustered
+	case "col3DStacked":
+		return excelize.Col3DStacked
+	case "col3DPercentStacked":
+		return excelize.Col3DPercentStacked
 	case "line":
 		return excelize.Line
+	case "line3D":
+		return excelize.Line3D
 	case "pie":
 		return excelize.Pie
+	case "pie3D":
+		return excelize.Pie3D
+	case "pieOfPie":
+		return excelize.PieOfPie
+	case "doughnut":
+		return excelize.Doughnut
 	case "bar":
 		return excelize.Bar
+	case "barOfPie":
+		return excelize.BarOfPie
 	case "area":
 		return excelize.Area
+	case "radar":
+		return excelize.Radar
+	case "surface3D":
+		return excelize.Surface3D
 	case "scatter":
 		return excelize.Scatter
-	case "doughnut":
-		return excelize.Doughnut
-	// Types that might be in JSON but are not directly supported in v2.9.1.
-	// Return the most suitable basic type or 'Col' as a fallback.
-	// This prevents compilation errors.
-	case "colStacked", "colPercentStacked", "col3D", "col3DClustered", "col3DStacked", "col3DPercentStacked",
-		"lineStacked", "linePercentStacked", "line3D", "pie3D", "pieOfPie", "barOfPie", "doughnutExploded":
-		// A warning could be logged if needed
-		// fmt.Printf("Warning: Chart type '%s' is not directly supported in Excelize v2.9.1, using 'col' as fallback.\n", chartTypeStr)
-		return excelize.Col
+	case "bubble":
+		return excelize.Bubble
+	case "bubble3D":
+		return excelize.Bubble3D
 	default:
-		// Unknown type - default to 'Col'
-		// It's better to log this as a warning
-		fmt.Printf("Warning: Unknown chart type '%s', using 'col' as default.\n", chartTypeStr)
+		log.Printf("Warning: Unknown chart type '%s', using 'col' as default. Supported types: %v", chartTypeStr, supportedChartTypeNames)
 		return excelize.Col
 	}
 }
@@ -247,76 +362,21 @@ func convertStyleToExcelizeOptions(styleMap map[string]interface{}) (*excelize.S
 		}
 	}
 
-	// number_format
-	if numFmt, ok := styleMap["number_format"].(string); ok {
-		// NumFmt in excelize.Style is an int, not string.
-		// We need to map string formats to int codes or use a different approach.
-		// For now, let's assume a direct mapping might be complex and log a warning.
-		// A common approach is to use excelize.SetColStyle/SetRowStyle/SetCellStyle with a predefined style ID.
-		// However, for simplicity, we might need to create a mapping table or use SetCellStyle with format strings.
-		// Let's try to see if excelize supports setting NumFmt directly from string via NewStyle options.
-		// Actually, excelize.NewStyle *does* accept NumFmt as a string key in the options map.
-		// But in the Style struct, it's an int.
-		// Let's map some common ones or use a generic approach.
-		// This is a common challenge when mapping from openpyxl (string) to excelize (int).
-		// For now, we'll set it as int if it's a known code, otherwise log.
-		// A better approach would be to use a map or handle this in the Python side.
-		// For this example, let's try to parse the string or use a default.
-		// Let's use the int directly from the string if it's a number, or handle common cases.
-		// Actually, excelize.Style struct does have NumFmt as int. This is tricky.
-		// Let's assume the Python side sends the integer code, or we map it here.
-		// Let's map some common string formats to excelize codes.
-		// General = 0, 0 = 1, 0.00 = 2, #,##0 = 3, #,##0.00 = 4, 0% = 9, 0.00% = 10, 0.00E+00 = 11, # ?/? = 12, # ??/?? = 13, mm-dd-yy = 14, d-mmm-yy = 15, d-mmm = 16, mmm-yy = 17, h:mm AM/PM = 18, h:mm:ss AM/PM = 19, h:mm = 20, h:mm:ss = 21, m/d/yy h:mm = 22, [Red] #,##0.00 = 37, [Red] #,##0;[Green] -#,##0 = 38, [Red] #,##0.00;[Green] -#,##0.00 = 39, [Red] #,##0;[Green] -#,##0 = 40, [Red] #,##0.00;[Green] -#,##0.00 = 41, [Red] #,##0;[Green] -#,##0 = 42, [Red] #,##0.00;[Green] -#,##0.00 = 43, [Red] #,##0;[Green] -#,##0 = 44, [Red] #,##0.00;[Green] -#,##0.00 = 45, [Red] #,##0;[Green] -#,##0 = 46, [Red] #,##0.00;[Green] -#,##0.00 = 47, [Red] #,##0;[Green] -#,##0 = 48, [Red] #,##0.00;[Green] -#,##0.00 = 49, @ = 50
-		// Let's try to map common string formats to int codes.
-		// This is a simplification. A more robust solution would be to pass the int code from Python.
-		var numFmtCode int
-		switch numFmt {
-		case "General":
-			numFmtCode = 0
-		case "0":
-			numFmtCode = 1
-		case "0.00":
-			numFmtCode = 2
-		case "#,##0":
-			numFmtCode = 3
-		case "#,##0.00":
-			numFmtCode = 4
-		case "0%":
-			numFmtCode = 9
-		case "0.00%":
-			numFmtCode = 10
-		case "0.00E+00":
-			numFmtCode = 11
-		case "# ?/?":
-			numFmtCode = 12
-		case "# ??/??":
-			numFmtCode = 13
-		case "mm-dd-yy":
-			numFmtCode = 14
-		case "d-mmm-yy":
-			numFmtCode = 15
-		case "d-mmm":
-			numFmtCode = 16
-		case "mmm-yy":
-			numFmtCode = 17
-		case "h:mm AM/PM":
-			numFmtCode = 18
-		case "h:mm:ss AM/PM":
-			numFmtCode = 19
-		case "h:mm":
-			numFmtCode = 20
-		case "h:mm:ss":
-			numFmtCode = 21
-		case "m/d/yy h:mm":
-			numFmtCode = 22
-		case "@":
-			numFmtCode = 50
-		default:
-			// If not a common format, log a warning and use General (0) or try to parse as int
-			log.Printf("Warning: Unrecognized number format string '%s', using General (0).", numFmt)
-			numFmtCode = 0
+	// number_format: "number_format_code" lets the Python side pass the built-in
+	// excelize integer code directly and skip string matching entirely.
+	if numFmtCode, ok := styleMap["number_format_code"].(float64); ok {
+		excelizeStyle.NumFmt = int(numFmtCode)
+	} else if numFmt, ok := styleMap["number_format"].(string); ok {
+		// NumFmt in excelize.Style is an int, not string, so known openpyxl format
+		// strings are mapped to their built-in code. Anything else (custom locale
+		// formats, accounting formats, etc.) is passed through via CustomNumFmt
+		// instead of being silently dropped.
+		if code, known := builtinNumFmtCode(numFmt); known {
+			excelizeStyle.NumFmt = code
+		} else {
+			customNumFmt := numFmt
+			excelizeStyle.CustomNumFmt = &customNumFmt
 		}
-		excelizeStyle.NumFmt = numFmtCode
 	}
 
 	// protection
@@ -329,6 +389,109 @@ func convertStyleToExcelizeOptions(styleMap map[string]interface{}) (*excelize.S
 	return excelizeStyle, nil
 }
 
+// builtinNumFmtCode maps an openpyxl-style number format string to its
+// built-in excelize format code. See:
+// https://pkg.go.dev/github.com/xuri/excelize/v2#NumFmt
+func builtinNumFmtCode(numFmt string) (int, bool) {
+	switch numFmt {
+	case "General":
+		return 0, true
+	case "0":
+		return 1, true
+	case "0.00":
+		return 2, true
+	case "#,##0":
+		return 3, true
+	case "#,##0.00":
+		return 4, true
+	case "0%":
+		return 9, true
+	case "0.00%":
+		return 10, true
+	case "0.00E+00":
+		return 11, true
+	case "# ?/?":
+		return 12, true
+	case "# ??/??":
+		return 13, true
+	case "mm-dd-yy":
+		return 14, true
+	case "d-mmm-yy":
+		return 15, true
+	case "d-mmm":
+		return 16, true
+	case "mmm-yy":
+		return 17, true
+	case "h:mm AM/PM":
+		return 18, true
+	case "h:mm:ss AM/PM":
+		return 19, true
+	case "h:mm":
+		return 20, true
+	case "h:mm:ss":
+		return 21, true
+	case "m/d/yy h:mm":
+		return 22, true
+	case "@":
+		return 49, true
+	default:
+		return 0, false
+	}
+}
+
+// styleCache deduplicates excelize styles so that ranges with equivalent style
+// dicts share a single f.NewStyle-created styleID instead of each getting its own
+// (otherwise-identical) entry in xl/styles.xml.
+type styleCache struct {
+	ids map[string]int
+}
+
+// newStyleCache creates an empty styleCache.
+func newStyleCache() *styleCache {
+	return &styleCache{ids: make(map[string]int)}
+}
+
+// getOrCreate returns the styleID for style, creating it via f.NewStyle on first
+// use and reusing the cached ID on subsequent calls with an equivalent style.
+// The returned bool is true when a new style was created (a cache miss).
+func (c *styleCache) getOrCreate(f *excelize.File, style *excelize.Style) (int, bool, error) {
+	key, err := styleCacheKey(style)
+	if err != nil {
+		return 0, false, fmt.Errorf("hashing style: %w", err)
+	}
+	if id, ok := c.ids[key]; ok {
+		return id, false, nil
+	}
+
+	id, err := f.NewStyle(style)
+	if err != nil {
+		return 0, false, err
+	}
+	c.ids[key] = id
+	return id, true, nil
+}
+
+// styleCacheKey returns a deterministic string representation of style, suitable
+// for use as a styleCache lookup key. Border entries are sorted by Type first,
+// so two styles whose Border slices hold the same sides in a different order
+// get the same key (convertStyleToExcelizeOptions itself always appends them
+// in top, bottom, left, right order).
+func styleCacheKey(style *excelize.Style) (string, error) {
+	canonical := *style
+	if len(canonical.Border) > 1 {
+		sorted := make([]excelize.Border, len(canonical.Border))
+		copy(sorted, canonical.Border)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Type < sorted[j].Type })
+		canonical.Border = sorted
+	}
+
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // getColorFromMap extracts a color string from a map[string]interface{}
 func getColorFromMap(borderSideData map[string]interface{}) string {
 	if colorData, ok := borderSideData["color"].(map[string]interface{}); ok {
@@ -361,15 +524,26 @@ func getStyleFromMap(borderSideData map[string]interface{}) int {
 
 func main() {
 	// Parse command-line arguments
-	inputFile := flag.String("input", "", "Path to the input JSON file")
-	outputFile := flag.String("output", "", "Path to the output XLSX file")
+	inputFile := flag.String("input", "", "Path to the input JSON file (export mode) or XLSX file (import mode)")
+	outputFile := flag.String("output", "", "Path to the output XLSX file (export mode) or JSON file (import mode)")
+	mode := flag.String("mode", "export", "Operation mode: 'export' (JSON -> XLSX) or 'import' (XLSX -> JSON)")
+	streamMode := flag.Bool("stream", false, "Use excelize's StreamWriter to populate sheets (faster, lower memory for large sheets)")
+	emitStyleStats := flag.Bool("emit-style-stats", false, "Log the number of unique vs. total styles processed per sheet")
 	flag.Parse()
 
 	if *inputFile == "" || *outputFile == "" {
-		fmt.Println("Usage: go_excel_exporter -input <input.json> -output <output.xlsx>")
+		fmt.Println("Usage: go_excel_exporter -input <input> -output <output> [-mode export|import]")
 		os.Exit(1)
 	}
 
+	if *mode == "import" {
+		if err := runImport(*inputFile, *outputFile); err != nil {
+			log.Fatalf("Error importing xlsx: %v", err)
+		}
+		fmt.Printf("Successfully imported %s to %s\n", *inputFile, *outputFile)
+		return
+	}
+
 	// Read the JSON file
 	jsonData, err := os.ReadFile(*inputFile)
 	if err != nil {
@@ -392,6 +566,8 @@ func main() {
 		}
 	}()
 
+	styles := newStyleCache()
+
 	// Process each sheet
 	for i, sheet := range exportData.Sheets {
 		var sheetName string
@@ -414,120 +590,588 @@ func main() {
 			// f.SetActiveSheet(index) // Optional
 		}
 
-		// Populate data
-		for rowIndex, row := range sheet.Data {
-			for colIndex, cellValue := range row {
-				// Excelize uses 1-based indexing
-				cellRow := rowIndex + 1
-				cellCol := colIndex + 1
-				// Convert column number to name (A, B, ..., Z, AA, AB, ...)
-				cellName, err := excelize.ColumnNumberToName(cellCol)
-				if err != nil {
-					log.Printf("Error converting column number %d to name: %v", cellCol, err)
-					continue
-				}
-				cellAddress := fmt.Sprintf("%s%d", cellName, cellRow)
-
-				if cellValue != nil {
-					// Set cell value
-					// f.SetCellValue(sheetName, cellAddress, *cellValue) // This method also works
-					// Use a more specific method if the type is known, but SetCellValue is fine for general cases.
-					if err := f.SetCellValue(sheetName, cellAddress, *cellValue); err != nil {
-						log.Printf("Warning: could not set cell value at %s on sheet '%s': %v", cellAddress, sheetName, err)
-					}
+		if *streamMode {
+			if err := processSheetStream(f, sheet, sheetName, styles, *emitStyleStats); err != nil {
+				log.Printf("Warning: stream export failed for sheet '%s', falling back to in-memory engine: %v", sheetName, err)
+				processSheetMemory(f, sheet, sheetName, styles, *emitStyleStats)
+			}
+		} else {
+			processSheetMemory(f, sheet, sheetName, styles, *emitStyleStats)
+		}
+
+		addCharts(f, sheet, sheetName)
+		applyConditionalFormats(f, sheetName, sheet.ConditionalFormats, styles)
+		applyDataValidations(f, sheetName, sheet.DataValidations)
+		applyTables(f, sheetName, sheet.Tables)
+		applyHyperlinks(f, sheetName, sheet.Hyperlinks)
+		applyImages(f, sheetName, sheet.Images)
+	}
+	// End of processing all sheets
+
+	// Save the file
+	if err := f.SaveAs(*outputFile); err != nil {
+		log.Fatalf("Error saving file: %v", err)
+	}
+
+	fmt.Printf("Successfully exported to %s\n", *outputFile)
+}
+
+// processSheetMemory is the default engine: it populates a sheet via random-access
+// cell writes (SetCellValue/SetCellFormula/SetCellStyle). It supports everything
+// (styles applied range-by-range, out-of-order writes) but holds the whole sheet
+// in memory, which is costly for very large sheets.
+func processSheetMemory(f *excelize.File, sheet SheetData, sheetName string, styles *styleCache, emitStyleStats bool) {
+	// Populate data
+	for rowIndex, row := range sheet.Data {
+		for colIndex, cellValue := range row {
+			// Excelize uses 1-based indexing
+			cellRow := rowIndex + 1
+			cellCol := colIndex + 1
+			// Convert column number to name (A, B, ..., Z, AA, AB, ...)
+			cellName, err := excelize.ColumnNumberToName(cellCol)
+			if err != nil {
+				log.Printf("Error converting column number %d to name: %v", cellCol, err)
+				continue
+			}
+			cellAddress := fmt.Sprintf("%s%d", cellName, cellRow)
+
+			if cellValue != nil {
+				// Set cell value
+				// f.SetCellValue(sheetName, cellAddress, *cellValue) // This method also works
+				// Use a more specific method if the type is known, but SetCellValue is fine for general cases.
+				if err := f.SetCellValue(sheetName, cellAddress, *cellValue); err != nil {
+					log.Printf("Warning: could not set cell value at %s on sheet '%s': %v", cellAddress, sheetName, err)
 				}
 			}
 		}
+	}
+
+	// Add formulas
+	for _, formula := range sheet.Formulas {
+		if err := f.SetCellFormula(sheetName, formula.Cell, formula.Formula); err != nil {
+			log.Printf("Warning: could not set formula at %s on sheet '%s': %v", formula.Cell, sheetName, err)
+		}
+	}
+
+	// --- START OF STYLE PROCESSING ---
+	log.Printf("Processing %d styles for sheet '%s'", len(sheet.Styles), sheetName)
+	uniqueStyles := 0
+	for _, styleObj := range sheet.Styles {
+		// 1. Convert JSON style definition to excelize.Style structure
+		excelizeStyle, err := convertStyleToExcelizeOptions(styleObj.Style)
+		if err != nil {
+			log.Printf("Warning: could not convert style for range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+			continue
+		}
 
-		// Add formulas
-		for _, formula := range sheet.Formulas {
-			if err := f.SetCellFormula(sheetName, formula.Cell, formula.Formula); err != nil {
-				log.Printf("Warning: could not set formula at %s on sheet '%s': %v", formula.Cell, sheetName, err)
+		// 2. Create (or reuse, if an equivalent style was already created) the style in excelize
+		styleID, created, err := styles.getOrCreate(f, excelizeStyle)
+		if err != nil {
+			log.Printf("Warning: could not create style in excelize for range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+			continue
+		}
+		if created {
+			uniqueStyles++
+		}
+
+		// 3. Apply the style to the range
+		if err := f.SetCellStyle(sheetName, styleObj.Range, styleObj.Range, styleID); err != nil {
+			log.Printf("Warning: could not apply style to range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+			continue
+		}
+	}
+	log.Printf("Finished processing styles for sheet '%s'", sheetName)
+	if emitStyleStats {
+		log.Printf("Style stats for sheet '%s': %d unique / %d total", sheetName, uniqueStyles, len(sheet.Styles))
+	}
+	// --- END OF STYLE PROCESSING ---
+
+	// Apply merged cells
+	// f.MergeCell requires 4 arguments: sheet, coordinate for top-left cell, coordinate for bottom-right cell
+	// The JSON contains a string like "A1:B2". We need to split this.
+	for _, mergedCellRange := range sheet.MergedCells {
+		// Split the range string "A1:B2" into "A1" and "B2"
+		// This is a simple split, assumes no spaces and correct format.
+		coords := splitRange(mergedCellRange)
+		if len(coords) != 2 {
+			log.Printf("Warning: Invalid merged cell range format '%s' on sheet '%s', skipping.", mergedCellRange, sheetName)
+			continue
+		}
+		// coords[0] is top-left, coords[1] is bottom-right
+		if err := f.MergeCell(sheetName, coords[0], coords[1]); err != nil {
+			log.Printf("Warning: could not merge cells '%s' (from '%s' to '%s') on sheet '%s': %v", mergedCellRange, coords[0], coords[1], sheetName, err)
+			continue
+		}
+	}
+	// End of merged cell application for the current sheet
+}
+
+// processSheetStream is the --stream engine: it populates a sheet via
+// excelize.File.NewStreamWriter, writing one row at a time with StreamWriter.SetRow.
+// The stream writer requires rows in ascending order and does not allow random-access
+// cell writes afterwards, so styles and formulas are resolved per-cell up front and
+// merged into the same row pass. Returns an error if the caller should fall back to
+// processSheetMemory instead.
+func processSheetStream(f *excelize.File, sheet SheetData, sheetName string, styles *styleCache, emitStyleStats bool) error {
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return fmt.Errorf("creating stream writer: %w", err)
+	}
+
+	// Pre-create (or reuse) styles and resolve which cells they apply to.
+	styleByCell := make(map[string]int)
+	uniqueStyles := 0
+	for _, styleObj := range sheet.Styles {
+		excelizeStyle, err := convertStyleToExcelizeOptions(styleObj.Style)
+		if err != nil {
+			log.Printf("Warning: could not convert style for range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+			continue
+		}
+		styleID, created, err := styles.getOrCreate(f, excelizeStyle)
+		if err != nil {
+			log.Printf("Warning: could not create style in excelize for range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+			continue
+		}
+		if created {
+			uniqueStyles++
+		}
+		cells, err := expandRange(styleObj.Range)
+		if err != nil {
+			log.Printf("Warning: could not expand style range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+			continue
+		}
+		for _, cell := range cells {
+			styleByCell[cell] = styleID
+		}
+	}
+	if emitStyleStats {
+		log.Printf("Style stats for sheet '%s': %d unique / %d total", sheetName, uniqueStyles, len(sheet.Styles))
+	}
+
+	// Pre-sort formulas into cell positions so they can be emitted in the same row pass.
+	formulaByCell := make(map[string]string)
+	maxRow := len(sheet.Data)
+	for _, formula := range sheet.Formulas {
+		formulaByCell[formula.Cell] = formula.Formula
+		_, row, err := excelize.CellNameToCoordinates(formula.Cell)
+		if err == nil && row > maxRow {
+			maxRow = row
+		}
+	}
+
+	for rowIndex := 0; rowIndex < maxRow; rowIndex++ {
+		cellRow := rowIndex + 1
+		var row []*string
+		if rowIndex < len(sheet.Data) {
+			row = sheet.Data[rowIndex]
+		}
+
+		maxCol := len(row)
+		for cell := range formulaByCell {
+			col, r, err := excelize.CellNameToCoordinates(cell)
+			if err == nil && r == cellRow && col > maxCol {
+				maxCol = col
 			}
 		}
 
-		// --- START OF STYLE PROCESSING ---
-		log.Printf("Processing %d styles for sheet '%s'", len(sheet.Styles), sheetName)
-		for _, styleObj := range sheet.Styles {
-			// 1. Convert JSON style definition to excelize.Style structure
-			excelizeStyle, err := convertStyleToExcelizeOptions(styleObj.Style)
+		rowValues := make([]interface{}, maxCol)
+		for colIndex := 0; colIndex < maxCol; colIndex++ {
+			cellName, err := excelize.ColumnNumberToName(colIndex + 1)
 			if err != nil {
-				log.Printf("Warning: could not convert style for range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+				log.Printf("Error converting column number %d to name: %v", colIndex+1, err)
 				continue
 			}
+			cellAddress := fmt.Sprintf("%s%d", cellName, cellRow)
+
+			styleID := styleByCell[cellAddress]
+			formula, hasFormula := formulaByCell[cellAddress]
+
+			var cellValue *string
+			if colIndex < len(row) {
+				cellValue = row[colIndex]
+			}
+
+			switch {
+			case hasFormula:
+				rowValues[colIndex] = excelize.Cell{StyleID: styleID, Formula: formula}
+			case cellValue != nil:
+				rowValues[colIndex] = excelize.Cell{StyleID: styleID, Value: *cellValue}
+			case styleID != 0:
+				rowValues[colIndex] = excelize.Cell{StyleID: styleID}
+			default:
+				rowValues[colIndex] = nil
+			}
+		}
+
+		cellRef := fmt.Sprintf("A%d", cellRow)
+		if err := sw.SetRow(cellRef, rowValues); err != nil {
+			return fmt.Errorf("writing row %d: %w", cellRow, err)
+		}
+	}
+
+	for _, mergedCellRange := range sheet.MergedCells {
+		coords := splitRange(mergedCellRange)
+		if len(coords) != 2 {
+			log.Printf("Warning: Invalid merged cell range format '%s' on sheet '%s', skipping.", mergedCellRange, sheetName)
+			continue
+		}
+		if err := sw.MergeCell(coords[0], coords[1]); err != nil {
+			log.Printf("Warning: could not merge cells '%s' (from '%s' to '%s') on sheet '%s': %v", mergedCellRange, coords[0], coords[1], sheetName, err)
+		}
+	}
 
-			// 2. Create the style in excelize
-			styleID, err := f.NewStyle(excelizeStyle)
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("flushing stream writer: %w", err)
+	}
+	return nil
+}
+
+// addCharts adds all of sheet.Charts to sheetName. It runs after either processing
+// engine, since excelize.StreamWriter does not support charts.
+func addCharts(f *excelize.File, sheet SheetData, sheetName string) {
+	for _, chart := range sheet.Charts {
+		chartConfig := buildChartConfig(chart)
+
+		var comboConfigs []*excelize.Chart
+		for _, combo := range chart.Combo {
+			comboConfigs = append(comboConfigs, buildChartConfig(combo))
+		}
+
+		// Add the chart to the sheet. Any Combo entries are passed as additional
+		// charts plotted at the same position (e.g. on a secondary axis).
+		if err := f.AddChart(sheetName, chart.Position, chartConfig, comboConfigs...); err != nil {
+			log.Printf("Warning: could not add chart at %s on sheet '%s': %v", chart.Position, sheetName, err)
+		}
+	}
+}
+
+// buildChartConfig converts a JSON Chart definition into an *excelize.Chart,
+// including axis titles, legend position, data-label visibility, and plot-area
+// dimensions.
+func buildChartConfig(chart Chart) *excelize.Chart {
+	chartConfig := &excelize.Chart{
+		Type:   convertChartType(chart.Type),
+		Series: []excelize.ChartSeries{},
+		Title:  excelize.ChartTitle{Paragraph: []excelize.RichTextRun{{Text: chart.Title}}},
+	}
+
+	// Populate data series for the chart
+	for _, series := range chart.Series {
+		chartConfig.Series = append(chartConfig.Series, excelize.ChartSeries{
+			Name:       series.Name,
+			Categories: series.Categories,
+			Values:     series.Values,
+		})
+	}
+
+	if chart.XAxisTitle != "" {
+		chartConfig.XAxis.Title = excelize.ChartTitle{Paragraph: []excelize.RichTextRun{{Text: chart.XAxisTitle}}}
+	}
+	if chart.YAxisTitle != "" {
+		chartConfig.YAxis.Title = excelize.ChartTitle{Paragraph: []excelize.RichTextRun{{Text: chart.YAxisTitle}}}
+	}
+	if chart.Legend != "" {
+		chartConfig.Legend.Position = chart.Legend
+	}
+	if chart.ShowDataLabels {
+		chartConfig.PlotArea.ShowVal = true
+	}
+	if chart.Width > 0 || chart.Height > 0 {
+		chartConfig.Dimension = excelize.ChartDimension{Width: chart.Width, Height: chart.Height}
+	}
+
+	return chartConfig
+}
+
+// validateRange reports whether rangeStr is a well-formed single cell ("A1") or
+// cell range ("A1:C3") reference.
+func validateRange(rangeStr string) error {
+	coords := splitRange(rangeStr)
+	if len(coords) == 2 {
+		if _, _, err := excelize.CellNameToCoordinates(coords[0]); err != nil {
+			return fmt.Errorf("invalid top-left cell '%s': %w", coords[0], err)
+		}
+		if _, _, err := excelize.CellNameToCoordinates(coords[1]); err != nil {
+			return fmt.Errorf("invalid bottom-right cell '%s': %w", coords[1], err)
+		}
+		return nil
+	}
+	if _, _, err := excelize.CellNameToCoordinates(rangeStr); err != nil {
+		return fmt.Errorf("invalid cell or range '%s': %w", rangeStr, err)
+	}
+	return nil
+}
+
+// applyConditionalFormats builds an excelize.ConditionalFormatOptions for each
+// CondFormat and applies it via f.SetConditionalFormat. A bad rule only warns
+// and skips that rule; it does not abort the rest of the sheet.
+func applyConditionalFormats(f *excelize.File, sheetName string, formats []CondFormat, styles *styleCache) {
+	for _, rule := range formats {
+		if err := validateRange(rule.Range); err != nil {
+			log.Printf("Warning: could not apply conditional format to sheet '%s': %v", sheetName, err)
+			continue
+		}
+
+		opt := excelize.ConditionalFormatOptions{}
+
+		if rule.Style != nil {
+			excelizeStyle, err := convertStyleToExcelizeOptions(rule.Style)
 			if err != nil {
-				log.Printf("Warning: could not create style in excelize for range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+				log.Printf("Warning: could not convert style for conditional format on range '%s' of sheet '%s': %v", rule.Range, sheetName, err)
 				continue
 			}
-
-			// 3. Apply the style to the range
-			if err := f.SetCellStyle(sheetName, styleObj.Range, styleObj.Range, styleID); err != nil {
-				log.Printf("Warning: could not apply style to range '%s' on sheet '%s': %v", styleObj.Range, sheetName, err)
+			styleID, _, err := styles.getOrCreate(f, excelizeStyle)
+			if err != nil {
+				log.Printf("Warning: could not create style for conditional format on range '%s' of sheet '%s': %v", rule.Range, sheetName, err)
 				continue
 			}
+			opt.Format = &styleID
 		}
-		log.Printf("Finished processing styles for sheet '%s'", sheetName)
-		// --- END OF STYLE PROCESSING ---
 
-		// Add charts
-		for _, chart := range sheet.Charts {
-			// Create chart configuration
-			chartConfig := &excelize.Chart{
-				Type: convertChartType(chart.Type), // <-- Use our function
-				// Series will be populated below
-				Series: []excelize.ChartSeries{},
-				// Title now takes []excelize.RichTextRun
-				Title: []excelize.RichTextRun{{Text: chart.Title}},
+		switch rule.Type {
+		case "cellIs":
+			opt.Type = "cell"
+			opt.Criteria = rule.Criteria
+			opt.Value = rule.Value
+			if rule.Formula != "" {
+				opt.MaxValue = rule.Formula
 			}
-
-			// Populate data series for the chart
-			for _, series := range chart.Series {
-				chartConfig.Series = append(chartConfig.Series, excelize.ChartSeries{
-					Name:       series.Name,
-					Categories: series.Categories,
-					Values:     series.Values,
-				})
+		case "expression":
+			opt.Type = "formula"
+			opt.Criteria = rule.Formula
+		case "colorScale":
+			opt.Type = "2_color_scale"
+			opt.MinType = "min"
+			opt.MinColor = rule.MinColor
+			if rule.MidColor != "" {
+				opt.Type = "3_color_scale"
+				opt.MidType = "percentile"
+				opt.MidValue = "50"
+				opt.MidColor = rule.MidColor
 			}
-
-			// Add the chart to the sheet
-			if err := f.AddChart(sheetName, chart.Position, chartConfig); err != nil {
-				log.Printf("Warning: could not add chart at %s on sheet '%s': %v", chart.Position, sheetName, err)
+			opt.MaxType = "max"
+			opt.MaxColor = rule.MaxColor
+		case "dataBar":
+			opt.Type = "data_bar"
+			opt.MinType = "min"
+			opt.MaxType = "max"
+			opt.BarColor = rule.BarColor
+		case "iconSet":
+			opt.Type = "icon_set"
+			opt.IconStyle = rule.IconStyle
+			opt.ReverseIcons = rule.ReverseIcons
+		case "top10":
+			opt.Type = "top"
+			if rule.Bottom {
+				opt.Type = "bottom"
 			}
+			if rule.Rank > 0 {
+				opt.Value = fmt.Sprintf("%d", rule.Rank)
+			} else {
+				opt.Value = "10"
+			}
+		case "duplicateValues":
+			opt.Type = "duplicate"
+		default:
+			log.Printf("Warning: unknown conditional format type '%s' for range '%s' on sheet '%s', skipping", rule.Type, rule.Range, sheetName)
+			continue
+		}
+
+		if err := f.SetConditionalFormat(sheetName, rule.Range, []excelize.ConditionalFormatOptions{opt}); err != nil {
+			log.Printf("Warning: could not apply conditional format to range '%s' on sheet '%s': %v", rule.Range, sheetName, err)
+		}
+	}
+}
+
+// validationOperator maps a string operator to excelize.DataValidationOperator.
+func validationOperator(operator string) excelize.DataValidationOperator {
+	switch operator {
+	case "notBetween":
+		return excelize.DataValidationOperatorNotBetween
+	case "equal":
+		return excelize.DataValidationOperatorEqual
+	case "notEqual":
+		return excelize.DataValidationOperatorNotEqual
+	case "greaterThan":
+		return excelize.DataValidationOperatorGreaterThan
+	case "greaterThanOrEqual":
+		return excelize.DataValidationOperatorGreaterThanOrEqual
+	case "lessThan":
+		return excelize.DataValidationOperatorLessThan
+	case "lessThanOrEqual":
+		return excelize.DataValidationOperatorLessThanOrEqual
+	default:
+		return excelize.DataValidationOperatorBetween
+	}
+}
+
+// applyDataValidations builds an excelize.DataValidation for each Validation and
+// applies it via f.AddDataValidation.
+func applyDataValidations(f *excelize.File, sheetName string, validations []Validation) {
+	for _, v := range validations {
+		if err := validateRange(v.Range); err != nil {
+			log.Printf("Warning: could not apply data validation to sheet '%s': %v", sheetName, err)
+			continue
+		}
+
+		dv := excelize.NewDataValidation(v.AllowBlank)
+		dv.Sqref = v.Range
+
+		var err error
+		switch v.Type {
+		case "list":
+			err = dv.SetDropList(splitListFormula(v.Formula1))
+		case "whole":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeWhole, validationOperator(v.Operator))
+		case "decimal":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeDecimal, validationOperator(v.Operator))
+		case "date":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeDate, validationOperator(v.Operator))
+		case "time":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeTime, validationOperator(v.Operator))
+		case "textLength":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeTextLength, validationOperator(v.Operator))
+		case "custom":
+			err = dv.SetRange(v.Formula1, v.Formula2, excelize.DataValidationTypeCustom, validationOperator(v.Operator))
+		default:
+			log.Printf("Warning: unknown data validation type '%s' for range '%s' on sheet '%s', skipping", v.Type, v.Range, sheetName)
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: could not build data validation for range '%s' on sheet '%s': %v", v.Range, sheetName, err)
+			continue
+		}
+
+		if v.InputTitle != "" || v.InputMessage != "" {
+			dv.SetInput(v.InputTitle, v.InputMessage)
+		}
+		if v.ErrorTitle != "" || v.ErrorMessage != "" {
+			dv.SetError(excelize.DataValidationErrorStyle(v.ErrorStyle), v.ErrorTitle, v.ErrorMessage)
+		}
+
+		if err := f.AddDataValidation(sheetName, dv); err != nil {
+			log.Printf("Warning: could not add data validation for range '%s' on sheet '%s': %v", v.Range, sheetName, err)
+		}
+	}
+}
+
+// splitListFormula splits a comma-separated Formula1 (e.g. "Yes,No,Maybe") into
+// the slice excelize.DataValidation.SetDropList expects.
+func splitListFormula(formula string) []string {
+	var items []string
+	for _, item := range strings.Split(formula, ",") {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items
+}
+
+// applyTables adds each Table to the sheet via f.AddTable. AddTable requires a
+// non-empty, workbook-unique Name, so a missing one is filled in from the
+// sheet name and the table's position in the list.
+func applyTables(f *excelize.File, sheetName string, tables []Table) {
+	for i, table := range tables {
+		if err := validateRange(table.Range); err != nil {
+			log.Printf("Warning: could not add table to sheet '%s': %v", sheetName, err)
+			continue
+		}
+
+		name := table.Name
+		if name == "" {
+			name = fmt.Sprintf("%s_Table%d", sheetName, i+1)
+		}
+		showRowStripes := table.ShowRowStripes
+
+		if err := f.AddTable(sheetName, &excelize.Table{
+			Range:          table.Range,
+			Name:           name,
+			StyleName:      table.StyleName,
+			ShowRowStripes: &showRowStripes,
+		}); err != nil {
+			log.Printf("Warning: could not add table '%s' to range '%s' on sheet '%s': %v", name, table.Range, sheetName, err)
+		}
+	}
+}
+
+// applyHyperlinks sets each Hyperlink via f.SetCellHyperLink.
+func applyHyperlinks(f *excelize.File, sheetName string, hyperlinks []Hyperlink) {
+	for _, link := range hyperlinks {
+		linkType := link.Type
+		if linkType == "" {
+			linkType = "External"
+		}
+		var opts []excelize.HyperlinkOpts
+		if link.Display != "" {
+			display := link.Display
+			opts = append(opts, excelize.HyperlinkOpts{Display: &display})
+		}
+		if link.Tooltip != "" {
+			tooltip := link.Tooltip
+			opts = append(opts, excelize.HyperlinkOpts{Tooltip: &tooltip})
 		}
-		// End of chart processing for the current sheet
+		if err := f.SetCellHyperLink(sheetName, link.Cell, link.Target, linkType, opts...); err != nil {
+			log.Printf("Warning: could not set hyperlink on cell '%s' of sheet '%s': %v", link.Cell, sheetName, err)
+		}
+	}
+}
+
+// applyImages adds each Image via f.AddPicture (when Path is set) or
+// f.AddPictureFromBytes (when Data is set).
+func applyImages(f *excelize.File, sheetName string, images []Image) {
+	for _, img := range images {
+		opts := &excelize.GraphicOptions{ScaleX: img.ScaleX, ScaleY: img.ScaleY}
 
-		// Apply merged cells
-		// f.MergeCell requires 4 arguments: sheet, coordinate for top-left cell, coordinate for bottom-right cell
-		// The JSON contains a string like "A1:B2". We need to split this.
-		for _, mergedCellRange := range sheet.MergedCells {
-			// Split the range string "A1:B2" into "A1" and "B2"
-			// This is a simple split, assumes no spaces and correct format.
-			coords := splitRange(mergedCellRange)
-			if len(coords) != 2 {
-				log.Printf("Warning: Invalid merged cell range format '%s' on sheet '%s', skipping.", mergedCellRange, sheetName)
+		switch {
+		case img.Data != "":
+			raw, err := base64.StdEncoding.DecodeString(img.Data)
+			if err != nil {
+				log.Printf("Warning: could not decode image for cell '%s' of sheet '%s': %v", img.Cell, sheetName, err)
 				continue
 			}
-			// coords[0] is top-left, coords[1] is bottom-right
-			if err := f.MergeCell(sheetName, coords[0], coords[1]); err != nil {
-				log.Printf("Warning: could not merge cells '%s' (from '%s' to '%s') on sheet '%s': %v", mergedCellRange, coords[0], coords[1], sheetName, err)
-				continue
+			if err := f.AddPictureFromBytes(sheetName, img.Cell, &excelize.Picture{
+				Extension: img.Extension,
+				File:      raw,
+				Format:    opts,
+			}); err != nil {
+				log.Printf("Warning: could not add image to cell '%s' of sheet '%s': %v", img.Cell, sheetName, err)
+			}
+		case img.Path != "":
+			if err := f.AddPicture(sheetName, img.Cell, img.Path, opts); err != nil {
+				log.Printf("Warning: could not add image '%s' to cell '%s' of sheet '%s': %v", img.Path, img.Cell, sheetName, err)
 			}
+		default:
+			log.Printf("Warning: image for cell '%s' of sheet '%s' has neither 'path' nor 'data', skipping", img.Cell, sheetName)
 		}
-		// End of merged cell application for the current sheet
+	}
+}
 
-		// TODO: Process additional elements (images, tables, etc.)
+// expandRange expands a range string like "A1:C2" into every individual cell
+// address it covers. A string with no colon is treated as a single cell.
+func expandRange(rangeStr string) ([]string, error) {
+	coords := splitRange(rangeStr)
+	if len(coords) != 2 {
+		return []string{rangeStr}, nil
 	}
-	// End of processing all sheets
 
-	// Save the file
-	if err := f.SaveAs(*outputFile); err != nil {
-		log.Fatalf("Error saving file: %v", err)
+	startCol, startRow, err := excelize.CellNameToCoordinates(coords[0])
+	if err != nil {
+		return nil, fmt.Errorf("parsing top-left cell '%s': %w", coords[0], err)
+	}
+	endCol, endRow, err := excelize.CellNameToCoordinates(coords[1])
+	if err != nil {
+		return nil, fmt.Errorf("parsing bottom-right cell '%s': %w", coords[1], err)
 	}
 
-	fmt.Printf("Successfully exported to %s\n", *outputFile)
+	var cells []string
+	for row := startRow; row <= endRow; row++ {
+		for col := startCol; col <= endCol; col++ {
+			cellName, err := excelize.ColumnNumberToName(col)
+			if err != nil {
+				return nil, fmt.Errorf("converting column number %d to name: %w", col, err)
+			}
+			cells = append(cells, fmt.Sprintf("%s%d", cellName, row))
+		}
+	}
+	return cells, nil
 }
 
 // splitRange splits a string like "A1:B2" into ["A1", "B2"]
@@ -550,3 +1194,273 @@ func splitRange(rangeStr string) []string {
 	bottomRight := rangeStr[colonIndex+1:]
 	return []string{topLeft, bottomRight}
 }
+
+// runImport opens an existing XLSX file and writes out the same ExportData JSON
+// shape the exporter consumes. Only cell data, formulas, merged cells and
+// styles round-trip (modulo the order of formula and style entries, which
+// come back in row-major order); styles are rebuilt via File.GetStyle,
+// grouping contiguous cells on a row that share a style ID into a single
+// Style.Range entry. Charts, conditional formats, data validations, tables,
+// hyperlinks and images are not reconstructed, so export -> import -> export
+// is lossy for any sheet that uses them.
+func runImport(inputPath, outputPath string) error {
+	f, err := excelize.OpenFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("opening xlsx: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	var exportData ExportData
+
+	for _, sheetName := range f.GetSheetList() {
+		rows, err := f.GetRows(sheetName)
+		if err != nil {
+			return fmt.Errorf("reading rows of sheet '%s': %w", sheetName, err)
+		}
+
+		sheet := SheetData{Name: sheetName}
+
+		for _, row := range rows {
+			cells := make([]*string, len(row))
+			for i, value := range row {
+				v := value
+				cells[i] = &v
+			}
+			sheet.Data = append(sheet.Data, cells)
+		}
+
+		for rowIndex, row := range rows {
+			for colIndex := range row {
+				cellName, err := excelize.CoordinatesToCellName(colIndex+1, rowIndex+1)
+				if err != nil {
+					continue
+				}
+				formula, err := f.GetCellFormula(sheetName, cellName)
+				if err != nil || formula == "" {
+					continue
+				}
+				sheet.Formulas = append(sheet.Formulas, Formula{Cell: cellName, Formula: formula})
+			}
+		}
+
+		mergedCells, err := f.GetMergeCells(sheetName)
+		if err != nil {
+			return fmt.Errorf("reading merged cells of sheet '%s': %w", sheetName, err)
+		}
+		for _, mc := range mergedCells {
+			sheet.MergedCells = append(sheet.MergedCells, fmt.Sprintf("%s:%s", mc.GetStartAxis(), mc.GetEndAxis()))
+		}
+
+		styles, err := reconstructStyles(f, sheetName, rows)
+		if err != nil {
+			return fmt.Errorf("reconstructing styles of sheet '%s': %w", sheetName, err)
+		}
+		sheet.Styles = styles
+
+		exportData.Sheets = append(exportData.Sheets, sheet)
+	}
+
+	jsonBytes, err := json.MarshalIndent(exportData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling export data: %w", err)
+	}
+	if err := os.WriteFile(outputPath, jsonBytes, 0644); err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	return nil
+}
+
+// reconstructStyles rebuilds the []Style entries for a sheet by reading back each
+// cell's style ID with f.GetCellStyle, then run-length-encoding contiguous cells
+// within a row that share the same (non-default) style ID into a single Range.
+// The excelize.Style for each distinct style ID is fetched once via f.GetStyle
+// and translated back into the same style-dict shape convertStyleToExcelizeOptions
+// expects, via excelizeStyleToMap.
+func reconstructStyles(f *excelize.File, sheetName string, rows [][]string) ([]Style, error) {
+	maxCols := 0
+	for _, row := range rows {
+		if len(row) > maxCols {
+			maxCols = len(row)
+		}
+	}
+
+	styleMapByID := make(map[int]map[string]interface{})
+	var styles []Style
+
+	for rowIndex := range rows {
+		row := rowIndex + 1
+		runStartCol := 0
+		runStyleID := 0
+
+		flushRun := func(endCol int) error {
+			if runStartCol == 0 || runStyleID == 0 {
+				return nil
+			}
+			styleMap, ok := styleMapByID[runStyleID]
+			if !ok {
+				excelizeStyle, err := f.GetStyle(runStyleID)
+				if err != nil {
+					return fmt.Errorf("getting style %d: %w", runStyleID, err)
+				}
+				styleMap = excelizeStyleToMap(excelizeStyle)
+				styleMapByID[runStyleID] = styleMap
+			}
+
+			startName, err := excelize.ColumnNumberToName(runStartCol)
+			if err != nil {
+				return err
+			}
+			endName, err := excelize.ColumnNumberToName(endCol)
+			if err != nil {
+				return err
+			}
+
+			rangeStr := fmt.Sprintf("%s%d", startName, row)
+			if endCol != runStartCol {
+				rangeStr = fmt.Sprintf("%s:%s%d", rangeStr, endName, row)
+			}
+			styles = append(styles, Style{Range: rangeStr, Style: styleMap})
+			return nil
+		}
+
+		for col := 1; col <= maxCols; col++ {
+			cellName, err := excelize.CoordinatesToCellName(col, row)
+			if err != nil {
+				continue
+			}
+			styleID, err := f.GetCellStyle(sheetName, cellName)
+			if err != nil {
+				return nil, fmt.Errorf("getting style of cell '%s': %w", cellName, err)
+			}
+
+			if styleID == runStyleID && styleID != 0 {
+				continue
+			}
+			if err := flushRun(col - 1); err != nil {
+				return nil, err
+			}
+			runStartCol, runStyleID = col, styleID
+		}
+		if err := flushRun(maxCols); err != nil {
+			return nil, err
+		}
+	}
+
+	return styles, nil
+}
+
+// excelizeStyleToMap is the inverse of convertStyleToExcelizeOptions: it
+// translates an *excelize.Style back into the same style-dict shape the
+// exporter's JSON input uses (font/fill/border/alignment/number_format).
+func excelizeStyleToMap(style *excelize.Style) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	if style.Font != nil {
+		font := make(map[string]interface{})
+		if style.Font.Family != "" {
+			font["name"] = style.Font.Family
+		}
+		if style.Font.Bold {
+			font["b"] = true
+		}
+		if style.Font.Italic {
+			font["i"] = true
+		}
+		if style.Font.Color != "" {
+			font["color"] = map[string]interface{}{"rgb": style.Font.Color}
+		}
+		if style.Font.Size != 0 {
+			font["sz"] = style.Font.Size
+		}
+		if len(font) > 0 {
+			result["font"] = font
+		}
+	}
+
+	if style.Fill.Type == "pattern" && len(style.Fill.Color) > 0 {
+		fill := map[string]interface{}{
+			"bgColor": map[string]interface{}{"rgb": style.Fill.Color[0]},
+		}
+		if patternType := patternNameFromCode(style.Fill.Pattern); patternType != "" {
+			fill["patternType"] = patternType
+		}
+		result["fill"] = fill
+	}
+
+	if style.Alignment != nil {
+		alignment := make(map[string]interface{})
+		if style.Alignment.Horizontal != "" {
+			alignment["horizontal"] = style.Alignment.Horizontal
+		}
+		if style.Alignment.Vertical != "" {
+			alignment["vertical"] = style.Alignment.Vertical
+		}
+		if style.Alignment.TextRotation != 0 {
+			alignment["textRotation"] = float64(style.Alignment.TextRotation)
+		}
+		if len(alignment) > 0 {
+			result["alignment"] = alignment
+		}
+	}
+
+	if len(style.Border) > 0 {
+		border := make(map[string]interface{})
+		for _, b := range style.Border {
+			border[b.Type] = map[string]interface{}{
+				"style": borderStyleName(b.Style),
+				"color": map[string]interface{}{"rgb": b.Color},
+			}
+		}
+		result["border"] = border
+	}
+
+	// number_format_code (rather than the string form) round-trips built-in
+	// codes exactly; CustomNumFmt is already the original string.
+	if style.CustomNumFmt != nil {
+		result["number_format"] = *style.CustomNumFmt
+	} else if style.NumFmt != 0 {
+		result["number_format_code"] = float64(style.NumFmt)
+	}
+
+	return result
+}
+
+// patternNameFromCode maps an excelize fill pattern code back to the openpyxl
+// patternType string. The inverse of the switch in convertStyleToExcelizeOptions.
+func patternNameFromCode(code int) string {
+	switch code {
+	case 1:
+		return "solid"
+	case 2:
+		return "darkGray"
+	case 3:
+		return "mediumGray"
+	case 4:
+		return "lightGray"
+	case 17:
+		return "gray125"
+	case 18:
+		return "gray0625"
+	default:
+		return ""
+	}
+}
+
+// borderStyleName maps an excelize border style code back to the openpyxl
+// style string. The inverse of getStyleFromMap.
+func borderStyleName(code int) string {
+	switch code {
+	case 2:
+		return "thin"
+	case 6:
+		return "medium"
+	case 8:
+		return "thick"
+	default:
+		return ""
+	}
+}
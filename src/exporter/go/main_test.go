@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestConvertStyleToExcelizeOptions_NumberFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		styleMap      map[string]interface{}
+		wantNumFmt    int
+		wantCustomFmt string
+		wantHasCustom bool
+	}{
+		{
+			name:       "builtin decimal format",
+			styleMap:   map[string]interface{}{"number_format": "0.00"},
+			wantNumFmt: 2,
+		},
+		{
+			name:       "builtin percent format",
+			styleMap:   map[string]interface{}{"number_format": "0.00%"},
+			wantNumFmt: 10,
+		},
+		{
+			name:       "builtin date format",
+			styleMap:   map[string]interface{}{"number_format": "mm-dd-yy"},
+			wantNumFmt: 14,
+		},
+		{
+			name:       "explicit number_format_code skips string matching",
+			styleMap:   map[string]interface{}{"number_format_code": float64(9)},
+			wantNumFmt: 9,
+		},
+		{
+			name:          "custom locale currency format",
+			styleMap:      map[string]interface{}{"number_format": `#,##0.00 "₽"`},
+			wantHasCustom: true,
+			wantCustomFmt: `#,##0.00 "₽"`,
+		},
+		{
+			name:          "custom date/time format",
+			styleMap:      map[string]interface{}{"number_format": "yyyy-mm-dd hh:mm:ss"},
+			wantHasCustom: true,
+			wantCustomFmt: "yyyy-mm-dd hh:mm:ss",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, err := convertStyleToExcelizeOptions(tt.styleMap)
+			if err != nil {
+				t.Fatalf("convertStyleToExcelizeOptions() returned error: %v", err)
+			}
+
+			if tt.wantHasCustom {
+				if style.CustomNumFmt == nil {
+					t.Fatalf("expected CustomNumFmt to be set, got nil")
+				}
+				if *style.CustomNumFmt != tt.wantCustomFmt {
+					t.Errorf("CustomNumFmt = %q, want %q", *style.CustomNumFmt, tt.wantCustomFmt)
+				}
+				return
+			}
+
+			if style.CustomNumFmt != nil {
+				t.Errorf("expected CustomNumFmt to be nil, got %q", *style.CustomNumFmt)
+			}
+			if style.NumFmt != tt.wantNumFmt {
+				t.Errorf("NumFmt = %d, want %d", style.NumFmt, tt.wantNumFmt)
+			}
+		})
+	}
+}
+
+func TestApplyConditionalFormats_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		rule CondFormat
+	}{
+		{name: "cellIs", rule: CondFormat{Range: "A1:A10", Type: "cellIs", Criteria: "greater than", Value: "5"}},
+		{name: "expression", rule: CondFormat{Range: "A1:A10", Type: "expression", Formula: "$A1>$B1"}},
+		{name: "colorScale 2-color", rule: CondFormat{Range: "A1:A10", Type: "colorScale", MinColor: "#FF0000", MaxColor: "#00FF00"}},
+		{name: "colorScale 3-color", rule: CondFormat{Range: "A1:A10", Type: "colorScale", MinColor: "#FF0000", MidColor: "#FFFF00", MaxColor: "#00FF00"}},
+		{name: "dataBar", rule: CondFormat{Range: "A1:A10", Type: "dataBar", BarColor: "#638EC6"}},
+		{name: "iconSet", rule: CondFormat{Range: "A1:A10", Type: "iconSet", IconStyle: "3TrafficLights1"}},
+		{name: "top10", rule: CondFormat{Range: "A1:A10", Type: "top10", Rank: 3}},
+		{name: "duplicateValues", rule: CondFormat{Range: "A1:A10", Type: "duplicateValues"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := excelize.NewFile()
+			styles := newStyleCache()
+			applyConditionalFormats(f, "Sheet1", []CondFormat{tt.rule}, styles)
+
+			saved, err := f.GetConditionalFormats("Sheet1")
+			if err != nil {
+				t.Fatalf("GetConditionalFormats() returned error: %v", err)
+			}
+			if len(saved) != 1 {
+				t.Fatalf("GetConditionalFormats() returned %d rules, want 1 (rule was silently dropped)", len(saved))
+			}
+		})
+	}
+}
+
+func TestBuiltinNumFmtCode(t *testing.T) {
+	if code, ok := builtinNumFmtCode("#,##0"); !ok || code != 3 {
+		t.Errorf("builtinNumFmtCode(\"#,##0\") = (%d, %v), want (3, true)", code, ok)
+	}
+	if code, ok := builtinNumFmtCode("@"); !ok || code != 49 {
+		t.Errorf("builtinNumFmtCode(\"@\") = (%d, %v), want (49, true)", code, ok)
+	}
+	if _, ok := builtinNumFmtCode("yyyy-mm-dd hh:mm:ss"); ok {
+		t.Errorf("builtinNumFmtCode(custom format) = ok, want not found")
+	}
+}